@@ -1,41 +1,93 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/awlx/kentik-mcp/pkg/config"
 	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/awlx/kentik-mcp/pkg/logging"
+	"github.com/awlx/kentik-mcp/pkg/metrics"
 	"github.com/awlx/kentik-mcp/pkg/tools"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
-	email := os.Getenv("KENTIK_EMAIL")
-	apiToken := os.Getenv("KENTIK_API_TOKEN")
-	region := os.Getenv("KENTIK_REGION")
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", "", "path to a YAML or JSON config file (default: $"+config.EnvConfigPath+")")
+	enableWrite := flag.Bool("enable-write", false, "register tools that change state in Kentik (alarm ack/clear, mitigations)")
+	readOnly := flag.Bool("read-only", false, "expose only read-only tools; overrides --enable-write")
+	allowTools := flag.String("allow-tools", "", "comma-separated tool name globs to expose (default: all)")
+	denyTools := flag.String("deny-tools", "", "comma-separated tool name globs to hide")
+	logLevel := flag.String("log-level", "", "log verbosity: debug, info, warn, error or off (default info)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Flags given on the command line take precedence over env and file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "enable-write":
+			cfg.Tools.EnableWrite = *enableWrite
+		case "read-only":
+			cfg.Tools.ReadOnly = *readOnly
+		case "allow-tools":
+			cfg.Tools.Allow = config.SplitList(*allowTools)
+		case "deny-tools":
+			cfg.Tools.Deny = config.SplitList(*denyTools)
+		case "log-level":
+			cfg.Logging.Level = *logLevel
+		}
+	})
 
-	if email == "" || apiToken == "" {
-		fmt.Fprintln(os.Stderr, "Error: KENTIK_EMAIL and KENTIK_API_TOKEN environment variables are required")
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid configuration:\n%v\n", err)
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "Usage:")
 		fmt.Fprintln(os.Stderr, "  export KENTIK_EMAIL=user@example.com")
 		fmt.Fprintln(os.Stderr, "  export KENTIK_API_TOKEN=your_api_token")
 		fmt.Fprintln(os.Stderr, "  export KENTIK_REGION=US  # optional, US or EU")
-		fmt.Fprintln(os.Stderr, "  kentik-mcp")
+		fmt.Fprintln(os.Stderr, "  kentik-mcp [--config kentik-mcp.yaml]")
 		os.Exit(1)
 	}
+	logger, logCloser, err := logging.New(logging.Config{
+		Level:  cfg.Logging.Level,
+		Format: cfg.Logging.Format,
+		File:   cfg.LogFilePath(),
+	}, func(s string) string { return kentik.Redact(s) })
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer logCloser.Close()
 
-	client := kentik.NewClient(kentik.Config{
-		Email:    email,
-		APIToken: apiToken,
-		Region:   region,
-	})
+	accounts, err := buildAccounts(cfg, true, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", kentik.Redact(err.Error()))
+		os.Exit(1)
+	}
+	watchCredentials(cfg, accounts)
 
 	s := server.NewMCPServer(
 		"Kentik MCP Server",
 		"1.0.0",
 		server.WithToolCapabilities(false),
 		server.WithRecovery(),
+		server.WithToolHandlerMiddleware(tools.MetricsMiddleware()),
+		server.WithToolHandlerMiddleware(tools.LoggingMiddleware(logger)),
 		server.WithInstructions("Kentik MCP Server provides access to the Kentik network observability platform. "+
 			"Available capabilities: query network flow data (traffic by source/dest IP, AS, geography, protocol, etc.), "+
 			"list and inspect devices, interfaces, sites, labels, tags, and users, "+
@@ -44,10 +96,193 @@ func main() {
 			"API docs: https://kb.kentik.com/docs/apis-overview"),
 	)
 
-	tools.RegisterAll(s, client)
+	opts := toolOptions(cfg)
+	opts.Logger = logger
+	registered, err := tools.RegisterAll(s, accounts, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Path != "" {
+		fmt.Fprintf(os.Stderr, "kentik-mcp: config %s\n", cfg.Path)
+	}
+	if len(accounts) > 1 {
+		fmt.Fprintf(os.Stderr, "kentik-mcp: accounts %s (primary %s)\n", strings.Join(accountNames(accounts), ", "), accounts[0].Name)
+	}
+	printToolSet(registered, opts)
+
+	if cfg.Metrics.Enabled && cfg.Metrics.Listen != "" {
+		go serveMetrics(cfg.Metrics.Listen, cfg.Metrics.Path)
+	}
 
-	if err := server.ServeStdio(s); err != nil {
+	switch cfg.Transport.Type {
+	case "http":
+		fmt.Fprintf(os.Stderr, "kentik-mcp: listening on %s%s (streamable HTTP)\n", cfg.Transport.Listen, cfg.Transport.Path)
+		mux := http.NewServeMux()
+		httpServer := server.NewStreamableHTTPServer(s,
+			server.WithEndpointPath(cfg.Transport.Path),
+			server.WithStreamableHTTPServer(&http.Server{Handler: mux}))
+		mux.Handle(cfg.Transport.Path, httpServer)
+		if cfg.Metrics.Enabled && cfg.Metrics.Listen == "" {
+			fmt.Fprintf(os.Stderr, "kentik-mcp: metrics on %s%s\n", cfg.Transport.Listen, cfg.Metrics.Path)
+			mux.Handle(cfg.Metrics.Path, metrics.Handler())
+		}
+		err = httpServer.Start(cfg.Transport.Listen)
+	default:
+		err = server.ServeStdio(s)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// serveMetrics serves the Prometheus endpoint on its own listener.
+func serveMetrics(addr, path string) {
+	fmt.Fprintf(os.Stderr, "kentik-mcp: metrics on %s%s\n", addr, path)
+	mux := http.NewServeMux()
+	mux.Handle(path, metrics.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "kentik-mcp: metrics listener: %v\n", err)
+	}
+}
+
+// buildAccounts creates a Kentik client per configured account, primary
+// first. Tokens are only read when readTokens is set. Each client logs its
+// requests to logger.
+func buildAccounts(cfg *config.Config, readTokens bool, logger *slog.Logger) ([]tools.Account, error) {
+	var accounts []tools.Account
+	for _, a := range cfg.Accounts {
+		var token string
+		if readTokens {
+			t, err := a.ReadToken()
+			if err != nil {
+				return nil, err
+			}
+			token = t
+		}
+		client := kentik.NewClient(kentik.Config{
+			Name:             a.Name,
+			Email:            a.Email,
+			APIToken:         token,
+			Region:           a.Region,
+			V5BaseURL:        a.V5BaseURL,
+			V6BaseURL:        a.V6BaseURL,
+			QueryConcurrency: cfg.RateLimits.QueryConcurrency,
+			MaxRetries:       clientMaxRetries(cfg.RateLimits.MaxRetries),
+			Timeout:          time.Duration(cfg.RateLimits.RequestTimeout),
+			Logger:           logger,
+		})
+		accounts = append(accounts, tools.Account{Name: a.Name, Email: a.Email, Region: a.Region, Client: client})
+	}
+	return accounts, nil
+}
+
+// clientMaxRetries maps max_retries onto kentik.Config, which reads 0 as
+// its default; max_retries: 0 disables retries.
+func clientMaxRetries(n int) int {
+	if n == 0 {
+		return -1
+	}
+	return n
+}
+
+func accountNames(accounts []tools.Account) []string {
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// toolOptions maps the configuration onto tools.Options.
+func toolOptions(cfg *config.Config) tools.Options {
+	opts := tools.Options{
+		EnableWrite:     cfg.Tools.EnableWrite,
+		ReadOnly:        cfg.Tools.ReadOnly,
+		Allow:           cfg.Tools.Allow,
+		Deny:            cfg.Tools.Deny,
+		DefaultLookback: cfg.Defaults.LookbackSeconds,
+		DefaultTopX:     cfg.Defaults.TopX,
+		DevicesTTL:      time.Duration(cfg.Cache.DevicesTTL),
+		SitesTTL:        time.Duration(cfg.Cache.SitesTTL),
+		ContextFile:     cfg.ContextFilePath(),
+		AdvisorMaxWait:  time.Duration(cfg.AIAdvisor.MaxWait),
+	}
+	for _, ic := range cfg.InterfaceClasses {
+		opts.InterfaceClasses = append(opts.InterfaceClasses, tools.InterfaceClass{
+			Name:        ic.Name,
+			Description: ic.Description,
+			Provider:    ic.Provider,
+		})
+	}
+	return opts
+}
+
+// runConfigCommand implements "kentik-mcp config validate [--config path]".
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "Usage: kentik-mcp config validate [--config path]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a YAML or JSON config file (default: $"+config.EnvConfigPath+")")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	source := cfg.Path
+	if source == "" {
+		source = "defaults and environment (no config file)"
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s is invalid:\n%v\n", source, err)
+		return 1
+	}
+	accounts, _ := buildAccounts(cfg, false, nil)
+	if _, err := tools.RegisterAll(server.NewMCPServer("validate", "0"), accounts, toolOptions(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s is invalid:\n%v\n", source, err)
+		return 1
+	}
+
+	fmt.Printf("✓ %s is valid\n", source)
+	fmt.Printf("  transport %s, query concurrency %d, context file %s\n",
+		cfg.Transport.Type, cfg.RateLimits.QueryConcurrency, cfg.ContextFilePath())
+	for i, a := range cfg.Accounts {
+		primary := ""
+		if i == 0 {
+			primary = ", primary"
+		}
+		fmt.Printf("  account %s: %s, region %s, token from %s%s\n", a.Name, kentik.MaskEmail(a.Email), a.Region, a.TokenSource(), primary)
+	}
+	return 0
+}
+
+// printToolSet writes the effective tool set to stderr, which MCP clients
+// show in their server logs.
+func printToolSet(registered []mcp.Tool, opts tools.Options) {
+	mode := "read-only"
+	if !opts.ReadOnly && opts.EnableWrite {
+		mode = "write enabled"
+	} else if !opts.ReadOnly {
+		mode = "default (no Kentik writes)"
+	}
+	fmt.Fprintf(os.Stderr, "kentik-mcp: %d tools, mode %s\n", len(registered), mode)
+	if opts.ReadOnly && opts.EnableWrite {
+		fmt.Fprintln(os.Stderr, "kentik-mcp: --read-only overrides --enable-write")
+	}
+	if len(opts.Allow) > 0 {
+		fmt.Fprintf(os.Stderr, "kentik-mcp: allow %s\n", strings.Join(opts.Allow, ","))
+	}
+	if len(opts.Deny) > 0 {
+		fmt.Fprintf(os.Stderr, "kentik-mcp: deny %s\n", strings.Join(opts.Deny, ","))
+	}
+	for _, t := range registered {
+		fmt.Fprintf(os.Stderr, "  %-34s %s\n", t.Name, tools.ToolMode(t))
+	}
+}
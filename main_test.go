@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/awlx/kentik-mcp/pkg/config"
+	"github.com/awlx/kentik-mcp/pkg/logging"
+)
+
+func TestClientMaxRetries(t *testing.T) {
+	tests := []struct{ in, want int }{
+		{0, -1}, // disabled, not the client default
+		{1, 1},
+		{3, 3},
+		{10, 10},
+	}
+	for _, tt := range tests {
+		if got := clientMaxRetries(tt.in); got != tt.want {
+			t.Errorf("clientMaxRetries(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildAccountsZeroRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	cfg := config.Default()
+	cfg.RateLimits.MaxRetries = 0
+	cfg.Accounts = []config.Account{{Name: "default", Email: "ops@example.com", Region: "US", V5BaseURL: srv.URL, V6BaseURL: srv.URL}}
+	accounts, err := buildAccounts(cfg, false, logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accounts[0].Client.V5(context.Background(), "GET", "/devices", nil); err == nil {
+		t.Fatal("V5 succeeded against a 429 server")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("max_retries 0 made %d requests, want 1", n)
+	}
+}
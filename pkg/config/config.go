@@ -0,0 +1,502 @@
+// Package config loads the kentik-mcp server configuration from a YAML or
+// JSON file and the environment.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigPath names the environment variable holding the config file path.
+const EnvConfigPath = "KENTIK_MCP_CONFIG"
+
+// Config is the complete server configuration.
+type Config struct {
+	Email    string `yaml:"email"`
+	APIToken string `yaml:"api_token"`
+	// APITokenFile holds the token when api_token is empty. It is re-read
+	// when it changes, so the token can be rotated without a restart.
+	APITokenFile string `yaml:"api_token_file"`
+	// CredentialCommand is run through the shell when neither api_token nor
+	// api_token_file is set; its trimmed stdout is used as the token.
+	CredentialCommand string `yaml:"credential_command"`
+	Region            string `yaml:"region"`
+	V5BaseURL         string `yaml:"v5_base_url"`
+	V6BaseURL         string `yaml:"v6_base_url"`
+
+	// Accounts lists several Kentik accounts. Without it the top-level
+	// credentials form a single account named "default".
+	Accounts       []Account `yaml:"accounts"`
+	PrimaryAccount string    `yaml:"primary_account"`
+
+	Transport        Transport        `yaml:"transport"`
+	RateLimits       RateLimits       `yaml:"rate_limits"`
+	Cache            Cache            `yaml:"cache"`
+	Defaults         Defaults         `yaml:"defaults"`
+	Tools            Tools            `yaml:"tools"`
+	Logging          Logging          `yaml:"logging"`
+	Metrics          Metrics          `yaml:"metrics"`
+	AIAdvisor        AIAdvisor        `yaml:"ai_advisor"`
+	ContextFile      string           `yaml:"context_file"`
+	InterfaceClasses []InterfaceClass `yaml:"interface_classes"`
+
+	// Path is the file the configuration was loaded from, if any.
+	Path string `yaml:"-"`
+}
+
+// Account is one Kentik account (company) the server can query.
+type Account struct {
+	Name              string `yaml:"name"`
+	Email             string `yaml:"email"`
+	APIToken          string `yaml:"api_token"`
+	APITokenFile      string `yaml:"api_token_file"`
+	CredentialCommand string `yaml:"credential_command"`
+	Region            string `yaml:"region"`
+	V5BaseURL         string `yaml:"v5_base_url"`
+	V6BaseURL         string `yaml:"v6_base_url"`
+}
+
+// Transport selects how MCP clients connect.
+type Transport struct {
+	Type   string `yaml:"type"`   // "stdio" (default) or "http"
+	Listen string `yaml:"listen"` // address for "http", default ":8080"
+	Path   string `yaml:"path"`   // endpoint path for "http", default "/mcp"
+}
+
+// RateLimits tunes the Kentik API client.
+type RateLimits struct {
+	QueryConcurrency int      `yaml:"query_concurrency"`
+	MaxRetries       int      `yaml:"max_retries"`
+	RequestTimeout   Duration `yaml:"request_timeout"`
+}
+
+// Cache sets how long inventory lookups are reused.
+type Cache struct {
+	DevicesTTL Duration `yaml:"devices_ttl"`
+	SitesTTL   Duration `yaml:"sites_ttl"`
+}
+
+// Defaults are used by query tools when a call does not set them.
+type Defaults struct {
+	LookbackSeconds int `yaml:"lookback_seconds"`
+	TopX            int `yaml:"topx"`
+}
+
+// Tools controls which tools are exposed.
+type Tools struct {
+	EnableWrite bool     `yaml:"enable_write"`
+	ReadOnly    bool     `yaml:"read_only"`
+	Allow       []string `yaml:"allow"`
+	Deny        []string `yaml:"deny"`
+}
+
+// Logging sets log verbosity, format and destination.
+type Logging struct {
+	Level  string `yaml:"level"`  // debug, info (default), warn, error or off
+	Format string `yaml:"format"` // text (default) or json
+	File   string `yaml:"file"`   // log file; empty logs to stderr
+}
+
+// Metrics controls the Prometheus endpoint, which is off by default as it
+// is unauthenticated. With the http transport it is served on the MCP
+// listener unless Listen is set; with stdio it needs Listen.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled"`
+	Listen  string `yaml:"listen"` // separate address, e.g. "127.0.0.1:9464"
+	Path    string `yaml:"path"`   // default "/metrics"
+}
+
+// AIAdvisor tunes the AI Advisor tools.
+type AIAdvisor struct {
+	// MaxWait is how long a question is polled before the session ID is
+	// returned for kentik_ai_advisor_status.
+	MaxWait Duration `yaml:"max_wait"`
+}
+
+// InterfaceClass classifies interfaces by description. Description is a
+// regular expression; Provider optionally extracts the provider name from
+// the description with its first capture group.
+type InterfaceClass struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Provider    string `yaml:"provider"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("5m") or
+// as a number of seconds.
+type Duration time.Duration
+
+// UnmarshalYAML accepts "90s", "5m" or a bare number of seconds.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if secs, err := strconv.ParseFloat(node.Value, 64); err == nil {
+		*d = Duration(secs * float64(time.Second))
+		return nil
+	}
+	v, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", node.Line, node.Value)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default returns the built-in configuration.
+func Default() *Config {
+	return &Config{
+		Region:     "US",
+		Transport:  Transport{Type: "stdio", Listen: ":8080", Path: "/mcp"},
+		RateLimits: RateLimits{QueryConcurrency: 4, MaxRetries: 3, RequestTimeout: Duration(120 * time.Second)},
+		Cache:      Cache{DevicesTTL: Duration(5 * time.Minute), SitesTTL: Duration(5 * time.Minute)},
+		Defaults:   Defaults{LookbackSeconds: 3600, TopX: 8},
+		Logging:    Logging{Level: "info", Format: "text"},
+		Metrics:    Metrics{Path: "/metrics"},
+		AIAdvisor:  AIAdvisor{MaxWait: Duration(90 * time.Second)},
+		InterfaceClasses: []InterfaceClass{
+			{Name: "transit", Description: `(?i)transit`},
+			{Name: "pni", Description: `(?i)\bpni\b`},
+			{Name: "ix", Description: `(?i)\b(ix|ixp)\b|peering`},
+			{Name: "core", Description: `(?i)\b(core|backbone)\b`},
+		},
+	}
+}
+
+// Load reads the config file at path, or at $KENTIK_MCP_CONFIG when path is
+// empty, on top of the defaults, then applies environment overrides. With
+// no file it returns the defaults plus environment.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv(EnvConfigPath)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config: %v", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parsing %s: %v", path, err)
+		}
+		cfg.Path = path
+	}
+	listed := len(cfg.Accounts) > 0
+	cfg.applyEnv()
+	cfg.normalizeAccounts()
+	if listed {
+		// Without accounts the environment already reached the top-level
+		// credentials; with them it overrides the primary account
+		cfg.Accounts[0].applyEnv()
+	}
+	return cfg, nil
+}
+
+// normalizeAccounts turns the top-level credentials into the "default"
+// account when no accounts are listed, defaults each account's region, and
+// moves the primary account to the front.
+func (c *Config) normalizeAccounts() {
+	if len(c.Accounts) == 0 {
+		c.Accounts = []Account{{
+			Name:              "default",
+			Email:             c.Email,
+			APIToken:          c.APIToken,
+			APITokenFile:      c.APITokenFile,
+			CredentialCommand: c.CredentialCommand,
+			Region:            c.Region,
+			V5BaseURL:         c.V5BaseURL,
+			V6BaseURL:         c.V6BaseURL,
+		}}
+	}
+	for i := range c.Accounts {
+		if c.Accounts[i].Region == "" {
+			c.Accounts[i].Region = c.Region
+		}
+	}
+	if c.PrimaryAccount == "" {
+		c.PrimaryAccount = c.Accounts[0].Name
+	}
+	for i, a := range c.Accounts {
+		if a.Name == c.PrimaryAccount && i > 0 {
+			c.Accounts[0], c.Accounts[i] = c.Accounts[i], c.Accounts[0]
+			break
+		}
+	}
+}
+
+// setEnvString sets *dst to the environment variable name if it is set.
+func setEnvString(dst *string, name string) {
+	if v := os.Getenv(name); v != "" {
+		*dst = v
+	}
+}
+
+// applyEnv overrides the account's credentials and endpoints with the
+// KENTIK_* environment variables that are set.
+func (a *Account) applyEnv() {
+	setEnvString(&a.Email, "KENTIK_EMAIL")
+	setEnvString(&a.APIToken, "KENTIK_API_TOKEN")
+	setEnvString(&a.APITokenFile, "KENTIK_API_TOKEN_FILE")
+	setEnvString(&a.CredentialCommand, "KENTIK_CREDENTIAL_COMMAND")
+	setEnvString(&a.Region, "KENTIK_REGION")
+	setEnvString(&a.V5BaseURL, "KENTIK_V5_BASE_URL")
+	setEnvString(&a.V6BaseURL, "KENTIK_V6_BASE_URL")
+}
+
+// applyEnv overrides file values with the environment variables that are set.
+func (c *Config) applyEnv() {
+	setEnvString(&c.Email, "KENTIK_EMAIL")
+	setEnvString(&c.APIToken, "KENTIK_API_TOKEN")
+	setEnvString(&c.APITokenFile, "KENTIK_API_TOKEN_FILE")
+	setEnvString(&c.CredentialCommand, "KENTIK_CREDENTIAL_COMMAND")
+	setEnvString(&c.Region, "KENTIK_REGION")
+	setEnvString(&c.V5BaseURL, "KENTIK_V5_BASE_URL")
+	setEnvString(&c.V6BaseURL, "KENTIK_V6_BASE_URL")
+	setEnvString(&c.Transport.Type, "KENTIK_MCP_TRANSPORT")
+	setEnvString(&c.Transport.Listen, "KENTIK_MCP_LISTEN")
+	setEnvString(&c.ContextFile, "KENTIK_MCP_CONTEXT_FILE")
+	setEnvString(&c.Logging.Level, "KENTIK_MCP_LOG_LEVEL")
+	setEnvString(&c.Logging.Format, "KENTIK_MCP_LOG_FORMAT")
+	setEnvString(&c.Logging.File, "KENTIK_MCP_LOG_FILE")
+	setEnvString(&c.Metrics.Listen, "KENTIK_MCP_METRICS_LISTEN")
+	if v, ok := envBool("KENTIK_MCP_METRICS"); ok {
+		c.Metrics.Enabled = v
+	}
+	if v, ok := envBool("KENTIK_MCP_ENABLE_WRITE"); ok {
+		c.Tools.EnableWrite = v
+	}
+	if v, ok := envBool("KENTIK_MCP_READ_ONLY"); ok {
+		c.Tools.ReadOnly = v
+	}
+	if v := os.Getenv("KENTIK_MCP_ALLOW_TOOLS"); v != "" {
+		c.Tools.Allow = SplitList(v)
+	}
+	if v := os.Getenv("KENTIK_MCP_DENY_TOOLS"); v != "" {
+		c.Tools.Deny = SplitList(v)
+	}
+}
+
+// envBool parses a boolean environment variable; ok is false when unset.
+func envBool(name string) (value, ok bool) {
+	switch strings.ToLower(os.Getenv(name)) {
+	case "1", "true", "yes", "on":
+		return true, true
+	case "0", "false", "no", "off":
+		return false, true
+	}
+	return false, false
+}
+
+// SplitList splits a comma-separated list, dropping empty items.
+func SplitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// TokenSource describes where an account's token comes from.
+func (a Account) TokenSource() string {
+	switch {
+	case a.APIToken != "":
+		return "api_token"
+	case a.APITokenFile != "":
+		return "api_token_file " + a.APITokenFile
+	case a.CredentialCommand != "":
+		return "credential_command"
+	}
+	return "none"
+}
+
+// Rotatable reports whether ReadToken can return a different token later.
+func (a Account) Rotatable() bool {
+	return a.APIToken == "" && (a.APITokenFile != "" || a.CredentialCommand != "")
+}
+
+// ReadToken returns the account's current API token: api_token as given,
+// else the contents of api_token_file, else the output of credential_command.
+func (a Account) ReadToken() (string, error) {
+	var token string
+	switch {
+	case a.APIToken != "":
+		return a.APIToken, nil
+	case a.APITokenFile != "":
+		data, err := os.ReadFile(expandHome(a.APITokenFile))
+		if err != nil {
+			return "", fmt.Errorf("account %q: reading api_token_file: %v", a.Name, err)
+		}
+		token = strings.TrimSpace(string(data))
+	case a.CredentialCommand != "":
+		out, err := exec.Command("sh", "-c", a.CredentialCommand).Output()
+		if err != nil {
+			return "", fmt.Errorf("account %q: credential command failed: %v", a.Name, err)
+		}
+		token = strings.TrimSpace(string(out))
+	default:
+		return "", fmt.Errorf("account %q: no API token configured", a.Name)
+	}
+	if token == "" {
+		return "", fmt.Errorf("account %q: %s is empty", a.Name, a.TokenSource())
+	}
+	return token, nil
+}
+
+// TokenFile returns the expanded api_token_file path, or "".
+func (a Account) TokenFile() string {
+	if a.APIToken != "" || a.APITokenFile == "" {
+		return ""
+	}
+	return expandHome(a.APITokenFile)
+}
+
+// expandHome expands a leading "~/" to the user's home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	return path
+}
+
+// ContextFilePath returns the saved-contexts file, expanding a leading "~".
+func (c *Config) ContextFilePath() string {
+	if c.ContextFile == "" {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, ".kentik-mcp-contexts.json")
+	}
+	return expandHome(c.ContextFile)
+}
+
+// LogFilePath returns the log file, expanding a leading "~"; empty means
+// stderr.
+func (c *Config) LogFilePath() string {
+	return expandHome(c.Logging.File)
+}
+
+// Validate reports every problem with the configuration. Credentials are
+// checked without reading token files or running credential commands.
+func (c *Config) Validate() error {
+	var errs []error
+	names := make(map[string]bool)
+	for i, a := range c.Accounts {
+		if a.Name == "" {
+			errs = append(errs, fmt.Errorf("accounts[%d]: name is required", i))
+		} else if names[a.Name] {
+			errs = append(errs, fmt.Errorf("accounts[%d]: duplicate name %q", i, a.Name))
+		}
+		names[a.Name] = true
+		errs = append(errs, a.validate()...)
+	}
+	if !names[c.PrimaryAccount] {
+		errs = append(errs, fmt.Errorf("primary_account %q is not a configured account", c.PrimaryAccount))
+	}
+	switch c.Transport.Type {
+	case "stdio":
+	case "http":
+		if c.Transport.Listen == "" {
+			errs = append(errs, errors.New("transport.listen is required for the http transport"))
+		}
+		if !strings.HasPrefix(c.Transport.Path, "/") {
+			errs = append(errs, fmt.Errorf("transport.path %q must start with /", c.Transport.Path))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("transport.type %q must be stdio or http", c.Transport.Type))
+	}
+	if c.Metrics.Enabled {
+		if !strings.HasPrefix(c.Metrics.Path, "/") {
+			errs = append(errs, fmt.Errorf("metrics.path %q must start with /", c.Metrics.Path))
+		}
+		if c.Transport.Type == "http" && c.Metrics.Listen == "" && c.Metrics.Path == c.Transport.Path {
+			errs = append(errs, fmt.Errorf("metrics.path %q conflicts with transport.path", c.Metrics.Path))
+		}
+	}
+	if c.RateLimits.QueryConcurrency < 1 || c.RateLimits.QueryConcurrency > 4 {
+		errs = append(errs, fmt.Errorf("rate_limits.query_concurrency must be 1-4 (Kentik allows 4), got %d", c.RateLimits.QueryConcurrency))
+	}
+	if c.RateLimits.MaxRetries < 0 {
+		errs = append(errs, errors.New("rate_limits.max_retries must not be negative"))
+	}
+	if c.RateLimits.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("rate_limits.request_timeout must be positive"))
+	}
+	if c.Cache.DevicesTTL < 0 || c.Cache.SitesTTL < 0 {
+		errs = append(errs, errors.New("cache TTLs must not be negative"))
+	}
+	if c.AIAdvisor.MaxWait <= 0 || time.Duration(c.AIAdvisor.MaxWait) > 10*time.Minute {
+		errs = append(errs, errors.New("ai_advisor.max_wait must be between 0 and 10m"))
+	}
+	if c.Defaults.LookbackSeconds <= 0 {
+		errs = append(errs, errors.New("defaults.lookback_seconds must be positive"))
+	}
+	if c.Defaults.TopX <= 0 {
+		errs = append(errs, errors.New("defaults.topx must be positive"))
+	}
+	if !strings.EqualFold(c.Logging.Level, "off") {
+		if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+			errs = append(errs, fmt.Errorf("logging.level: %v", err))
+		}
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("logging.format %q must be text or json", c.Logging.Format))
+	}
+	for _, p := range append(append([]string{}, c.Tools.Allow...), c.Tools.Deny...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid tool pattern %q", p))
+		}
+	}
+	seen := make(map[string]bool)
+	for i, ic := range c.InterfaceClasses {
+		if ic.Name == "" {
+			errs = append(errs, fmt.Errorf("interface_classes[%d]: name is required", i))
+		} else if seen[ic.Name] {
+			errs = append(errs, fmt.Errorf("interface_classes[%d]: duplicate name %q", i, ic.Name))
+		}
+		seen[ic.Name] = true
+		if _, err := regexp.Compile(ic.Description); err != nil || ic.Description == "" {
+			errs = append(errs, fmt.Errorf("interface_classes[%d] (%s): invalid description pattern %q", i, ic.Name, ic.Description))
+		}
+		if ic.Provider != "" {
+			re, err := regexp.Compile(ic.Provider)
+			if err != nil || re.NumSubexp() < 1 {
+				errs = append(errs, fmt.Errorf("interface_classes[%d] (%s): provider pattern needs a capture group", i, ic.Name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks an account's credentials and endpoints.
+func (a Account) validate() []error {
+	var errs []error
+	if a.Email == "" {
+		errs = append(errs, fmt.Errorf("account %q: email is required (config email or KENTIK_EMAIL)", a.Name))
+	}
+	if a.TokenSource() == "none" {
+		errs = append(errs, fmt.Errorf("account %q: api_token, api_token_file or credential_command is required (or KENTIK_API_TOKEN)", a.Name))
+	}
+	if r := strings.ToUpper(a.Region); r != "US" && r != "EU" && (a.V5BaseURL == "" || a.V6BaseURL == "") {
+		errs = append(errs, fmt.Errorf("account %q: region %q must be US or EU unless both base URLs are set", a.Name, a.Region))
+	}
+	for _, u := range []string{a.V5BaseURL, a.V6BaseURL} {
+		if u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+			errs = append(errs, fmt.Errorf("account %q: base URL %q must start with http:// or https://", a.Name, u))
+		}
+	}
+	return errs
+}
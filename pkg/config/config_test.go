@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the environment variables Load reads, for the test.
+func clearEnv(t *testing.T) {
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "KENTIK_") {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, yaml string) string {
+	path := filepath.Join(t.TempDir(), "kentik-mcp.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEnvPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		primary Account
+		others  []Account
+	}{
+		{
+			name:    "env only",
+			env:     map[string]string{"KENTIK_EMAIL": "env@example.com", "KENTIK_API_TOKEN": "envtoken"},
+			primary: Account{Name: "default", Email: "env@example.com", APIToken: "envtoken", Region: "US"},
+		},
+		{
+			name:    "env overrides top-level file values",
+			yaml:    "email: file@example.com\napi_token: filetoken\nregion: EU\n",
+			env:     map[string]string{"KENTIK_API_TOKEN": "envtoken"},
+			primary: Account{Name: "default", Email: "file@example.com", APIToken: "envtoken", Region: "EU"},
+		},
+		{
+			name:    "file values without env",
+			yaml:    "email: file@example.com\napi_token_file: ~/token\n",
+			primary: Account{Name: "default", Email: "file@example.com", APITokenFile: "~/token", Region: "US"},
+		},
+		{
+			name: "env overrides the primary of listed accounts only",
+			yaml: "primary_account: eu\naccounts:\n" +
+				"  - {name: us, email: us@example.com, api_token: ustoken}\n" +
+				"  - {name: eu, email: eu@example.com, api_token: eutoken, region: EU}\n",
+			env:     map[string]string{"KENTIK_API_TOKEN": "envtoken", "KENTIK_REGION": "US"},
+			primary: Account{Name: "eu", Email: "eu@example.com", APIToken: "envtoken", Region: "US"},
+			others:  []Account{{Name: "us", Email: "us@example.com", APIToken: "ustoken", Region: "US"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tt.yaml != "" {
+				path = writeConfig(t, tt.yaml)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append([]Account{tt.primary}, tt.others...)
+			if len(cfg.Accounts) != len(want) {
+				t.Fatalf("accounts = %+v, want %+v", cfg.Accounts, want)
+			}
+			for i, a := range cfg.Accounts {
+				if a != want[i] {
+					t.Errorf("accounts[%d] = %+v, want %+v", i, a, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadEnvSettings(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("KENTIK_MCP_TRANSPORT", "http")
+	t.Setenv("KENTIK_MCP_READ_ONLY", "yes")
+	t.Setenv("KENTIK_MCP_ENABLE_WRITE", "off")
+	t.Setenv("KENTIK_MCP_DENY_TOOLS", "kentik_ai_*, ,kentik_list_users")
+	cfg, err := Load(writeConfig(t, "tools:\n  enable_write: true\ntransport:\n  type: stdio\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Transport.Type != "http" || !cfg.Tools.ReadOnly || cfg.Tools.EnableWrite {
+		t.Errorf("transport %q, read_only %v, enable_write %v; want http, true, false", cfg.Transport.Type, cfg.Tools.ReadOnly, cfg.Tools.EnableWrite)
+	}
+	if got := strings.Join(cfg.Tools.Deny, ","); got != "kentik_ai_*,kentik_list_users" {
+		t.Errorf("deny = %q", got)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	clearEnv(t)
+	if _, err := Load(writeConfig(t, "emial: typo@example.com\n")); err == nil {
+		t.Error("Load accepted an unknown field")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string // substrings of the error; none means valid
+	}{
+		{"defaults with credentials", func(c *Config) {}, nil},
+		{"zero retries", func(c *Config) { c.RateLimits.MaxRetries = 0 }, nil},
+		{"negative retries", func(c *Config) { c.RateLimits.MaxRetries = -1 }, []string{"max_retries must not be negative"}},
+		{"concurrency above the Kentik limit", func(c *Config) { c.RateLimits.QueryConcurrency = 5 }, []string{"query_concurrency must be 1-4"}},
+		{"missing credentials", func(c *Config) { c.Accounts[0].Email, c.Accounts[0].APIToken = "", "" }, []string{"email is required", "api_token, api_token_file or credential_command is required"}},
+		{"unknown region", func(c *Config) { c.Accounts[0].Region = "APAC" }, []string{`region "APAC" must be US or EU`}},
+		{"custom region with base URLs", func(c *Config) {
+			c.Accounts[0].Region, c.Accounts[0].V5BaseURL, c.Accounts[0].V6BaseURL = "APAC", "https://v5.example.com", "https://v6.example.com"
+		}, nil},
+		{"duplicate account", func(c *Config) { c.Accounts = append(c.Accounts, c.Accounts[0]) }, []string{`duplicate name "default"`}},
+		{"unknown primary", func(c *Config) { c.PrimaryAccount = "eu" }, []string{`primary_account "eu"`}},
+		{"bad transport", func(c *Config) { c.Transport.Type = "sse" }, []string{`transport.type "sse"`}},
+		{"metrics path clash", func(c *Config) {
+			c.Transport.Type, c.Metrics.Enabled, c.Metrics.Path = "http", true, "/mcp"
+		}, []string{"conflicts with transport.path"}},
+		{"disabled metrics are not checked", func(c *Config) { c.Metrics.Path = "metrics" }, nil},
+		{"advisor wait too long", func(c *Config) { c.AIAdvisor.MaxWait = Duration(11 * time.Minute) }, []string{"ai_advisor.max_wait"}},
+		{"bad log level", func(c *Config) { c.Logging.Level = "verbose" }, []string{"logging.level"}},
+		{"logging off", func(c *Config) { c.Logging.Level = "off" }, nil},
+		{"bad tool pattern", func(c *Config) { c.Tools.Deny = []string{"kentik_["} }, []string{`invalid tool pattern "kentik_["`}},
+		{"provider without group", func(c *Config) { c.InterfaceClasses[0].Provider = "transit" }, []string{"provider pattern needs a capture group"}},
+		{"several problems", func(c *Config) { c.Defaults.TopX, c.Defaults.LookbackSeconds = 0, 0 }, []string{"defaults.topx", "defaults.lookback_seconds"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.Email, c.APIToken = "ops@example.com", "secret"
+			c.normalizeAccounts()
+			tt.modify(c)
+			err := c.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.want)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("Validate() = %v, want it to contain %q", err, w)
+				}
+			}
+		})
+	}
+}
@@ -2,28 +2,77 @@ package kentik
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/logging"
+	"github.com/awlx/kentik-mcp/pkg/metrics"
 )
 
 // Config holds the credentials and region for authenticating with Kentik.
 type Config struct {
+	// Name identifies the account in logs.
+	Name     string
 	Email    string
 	APIToken string
 	Region   string // "US" (default) or "EU"
+
+	// V5BaseURL and V6BaseURL override the regional API endpoints.
+	V5BaseURL string
+	V6BaseURL string
+
+	// QueryConcurrency caps concurrent Query API requests (default and
+	// maximum QueryConcurrency). MaxRetries bounds retries of rate-limited
+	// requests (default 3, negative for none). Timeout is the per-request
+	// timeout (default 120s).
+	QueryConcurrency int
+	MaxRetries       int
+	Timeout          time.Duration
+
+	// Logger receives a record per upstream request. Default: discard.
+	Logger *slog.Logger
 }
 
+// QueryConcurrency is Kentik's limit on concurrent Query API requests.
+const QueryConcurrency = 4
+
+// defaultMaxRetries bounds how often a rate-limited (HTTP 429) request is retried.
+const defaultMaxRetries = 3
+
+// maxRetryDelay caps the wait before retrying a rate-limited request,
+// whatever Retry-After asks for.
+const maxRetryDelay = 60 * time.Second
+
 // Client is an HTTP client for the Kentik API.
 type Client struct {
+	name   string
+	logger *slog.Logger
+
+	credMu   sync.RWMutex
 	email    string
 	apiToken string
-	v5Base   string
-	v6Base   string
-	http     *http.Client
+	// oldTokens are the last tokens replaced by SetAPIToken, still redacted
+	// as requests started before the rotation may echo them.
+	oldTokens []string
+
+	v5Base string
+	v6Base string
+	http   *http.Client
+
+	// maxRetries bounds how often a rate-limited (HTTP 429) request is retried.
+	maxRetries int
+
+	// querySlots holds one token per in-flight /query/ request so that
+	// concurrent tool calls never exceed QueryConcurrency.
+	querySlots chan struct{}
 }
 
 // NewClient creates a new Kentik API client.
@@ -37,71 +86,225 @@ func NewClient(cfg Config) *Client {
 		v5Base = "https://api.kentik.com/api/v5"
 		v6Base = "https://grpc.api.kentik.com"
 	}
+	if cfg.V5BaseURL != "" {
+		v5Base = strings.TrimSuffix(cfg.V5BaseURL, "/")
+	}
+	if cfg.V6BaseURL != "" {
+		v6Base = strings.TrimSuffix(cfg.V6BaseURL, "/")
+	}
+	concurrency := QueryConcurrency
+	if cfg.QueryConcurrency > 0 && cfg.QueryConcurrency < QueryConcurrency {
+		concurrency = cfg.QueryConcurrency
+	}
+	retries := defaultMaxRetries
+	if cfg.MaxRetries > 0 {
+		retries = cfg.MaxRetries
+	} else if cfg.MaxRetries < 0 {
+		retries = 0
+	}
+	timeout := 120 * time.Second
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Discard()
+	}
 	return &Client{
+		name:     cfg.Name,
+		logger:   logger,
 		email:    cfg.Email,
 		apiToken: cfg.APIToken,
 		v5Base:   v5Base,
 		v6Base:   v6Base,
 		http: &http.Client{
-			Timeout: 120 * time.Second,
+			Timeout: timeout,
 		},
+		maxRetries: retries,
+		querySlots: make(chan struct{}, concurrency),
+	}
+}
+
+// maxOldTokens bounds how many replaced tokens stay redacted.
+const maxOldTokens = 4
+
+// SetAPIToken replaces the API token used by subsequent requests.
+func (c *Client) SetAPIToken(token string) {
+	c.credMu.Lock()
+	defer c.credMu.Unlock()
+	if c.apiToken != "" && c.apiToken != token {
+		c.oldTokens = append(c.oldTokens, c.apiToken)
+		if len(c.oldTokens) > maxOldTokens {
+			c.oldTokens = c.oldTokens[len(c.oldTokens)-maxOldTokens:]
+		}
 	}
+	c.apiToken = token
+}
+
+func (c *Client) credentials() (email, token string) {
+	c.credMu.RLock()
+	defer c.credMu.RUnlock()
+	return c.email, c.apiToken
 }
 
 func (c *Client) headers() map[string]string {
+	email, token := c.credentials()
 	return map[string]string{
-		"X-CH-Auth-Email":     c.email,
-		"X-CH-Auth-API-Token": c.apiToken,
+		"X-CH-Auth-Email":     email,
+		"X-CH-Auth-API-Token": token,
 		"Content-Type":        "application/json",
 	}
 }
 
-func (c *Client) doRequest(method, url string, body interface{}) (json.RawMessage, error) {
-	var reqBody io.Reader
+func (c *Client) doRequest(ctx context.Context, api, method, path string, body interface{}) (json.RawMessage, error) {
+	var payload []byte
 	if body != nil {
 		b, err := json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("marshal request body: %w", err)
 		}
-		reqBody = bytes.NewReader(b)
+		payload = b
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+	base := c.v5Base
+	if api == "v6" {
+		base = c.v6Base
 	}
-	for k, v := range c.headers() {
-		req.Header.Set(k, v)
+	log := c.logger.With("trace_id", logging.TraceID(ctx), "account", c.name, "api", api, "method", method, "path", path)
+	if payload != nil {
+		log.Debug("kentik request body", "body", c.redact(string(payload)))
 	}
 
-	resp, err := c.http.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
+	endpoint := endpointClass(api, path)
+	slotted := api == "v5" && strings.HasPrefix(path, "/query/")
+	start := time.Now()
+	defer func() { metrics.UpstreamDuration.ObserveSince(start, c.name, endpoint) }()
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, base+path, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %s", c.redact(err.Error()))
+		}
+		for k, v := range c.headers() {
+			req.Header.Set(k, v)
+		}
+
+		if slotted {
+			if err := c.acquireQuerySlot(ctx); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if slotted {
+				<-c.querySlots
+			}
+			err = fmt.Errorf("execute request: %s", c.redact(err.Error()))
+			metrics.UpstreamRequests.Inc(c.name, endpoint, "error")
+			log.Warn("kentik request failed", "latency_ms", time.Since(start).Milliseconds(), "retries", attempt, "error", err.Error())
+			return nil, err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		// The slot is released before any retry wait so a throttled
+		// request does not hold back other queries
+		if slotted {
+			<-c.querySlots
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read response body: %w", err)
+		}
+
+		delay := retryDelay(resp.Header.Get("Retry-After"), attempt)
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.maxRetries && fitsDeadline(ctx, delay) {
+			metrics.UpstreamRequests.Inc(c.name, endpoint, strconv.Itoa(resp.StatusCode))
+			metrics.UpstreamRetries.Inc(c.name, endpoint)
+			log.Warn("kentik rate limited, retrying", "retry_in", delay.String(), "attempt", attempt+1)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		metrics.UpstreamRequests.Inc(c.name, endpoint, strconv.Itoa(resp.StatusCode))
+		attrs := []any{"status", resp.StatusCode, "latency_ms", time.Since(start).Milliseconds(),
+			"bytes", len(respBody), "retries", attempt}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err := fmt.Errorf("API error %d: %s", resp.StatusCode, c.errorBody(respBody))
+			log.Warn("kentik request", append(attrs, "error", err.Error())...)
+			return nil, err
+		}
+		log.Info("kentik request", attrs...)
+		return json.RawMessage(respBody), nil
 	}
-	defer resp.Body.Close()
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response body: %w", err)
+// endpointClass groups request paths for metrics by API and first path
+// segment, e.g. "v5/query" or "v6/synthetics".
+func endpointClass(api, path string) string {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	seg, _, _ = strings.Cut(seg, "?")
+	return api + "/" + seg
+}
+
+// retryDelay honours a Retry-After header in seconds, falling back to
+// exponential backoff starting at one second; either is capped at
+// maxRetryDelay.
+func retryDelay(retryAfter string, attempt int) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && secs > 0 {
+		// Clamp before converting so huge values cannot overflow
+		return time.Duration(min(secs, int(maxRetryDelay/time.Second))) * time.Second
 	}
+	return min(time.Second<<min(attempt, 6), maxRetryDelay)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+// fitsDeadline reports whether waiting d still leaves time before the
+// context deadline; retrying past it would only fail later.
+func fitsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+// acquireQuerySlot waits for one of the client's Query API slots.
+func (c *Client) acquireQuerySlot(ctx context.Context) error {
+	waitStart := time.Now()
+	select {
+	case c.querySlots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	wait := time.Since(waitStart)
+	metrics.QuerySlotWait.Observe(wait.Seconds(), c.name)
+	if wait > 100*time.Millisecond {
+		c.logger.Debug("waited for query slot", "trace_id", logging.TraceID(ctx), "account", c.name, "wait_ms", wait.Milliseconds())
 	}
+	return nil
+}
+
+// Name returns the account name the client was configured with.
+func (c *Client) Name() string {
+	return c.name
+}
 
-	return json.RawMessage(respBody), nil
+// QueryConcurrency returns the client's cap on concurrent Query API requests.
+func (c *Client) QueryConcurrency() int {
+	return cap(c.querySlots)
 }
 
 // V5 makes a request to the Kentik V5 REST API.
 // path should start with "/" e.g. "/devices".
-func (c *Client) V5(method, path string, body interface{}) (json.RawMessage, error) {
-	url := c.v5Base + path
-	return c.doRequest(method, url, body)
+// Requests under /query/ hold one of the query slots while in flight.
+func (c *Client) V5(ctx context.Context, method, path string, body interface{}) (json.RawMessage, error) {
+	return c.doRequest(ctx, "v5", method, path, body)
 }
 
 // V6 makes a request to the Kentik V6 gRPC-gateway API.
 // path should be the full path e.g. "/synthetics/v202309/tests".
-func (c *Client) V6(method, path string, body interface{}) (json.RawMessage, error) {
-	url := c.v6Base + path
-	return c.doRequest(method, url, body)
+func (c *Client) V6(ctx context.Context, method, path string, body interface{}) (json.RawMessage, error) {
+	return c.doRequest(ctx, "v6", method, path, body)
 }
@@ -0,0 +1,74 @@
+package kentik
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		retryAfter string
+		attempt    int
+		want       time.Duration
+	}{
+		{"5", 0, 5 * time.Second},
+		{" 30 ", 2, 30 * time.Second},
+		{"60", 0, maxRetryDelay},
+		{"3600", 0, maxRetryDelay},                // capped
+		{"9223372036854775807", 0, maxRetryDelay}, // no overflow
+		{"0", 1, 2 * time.Second},                 // backoff
+		{"-5", 0, time.Second},
+		{"Wed, 21 Oct 2026 07:28:00 GMT", 2, 4 * time.Second}, // HTTP date: backoff
+		{"", 3, 8 * time.Second},
+		{"", 6, maxRetryDelay},
+		{"", 40, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.retryAfter, tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%q, %d) = %v, want %v", tt.retryAfter, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestFitsDeadline(t *testing.T) {
+	if !fitsDeadline(context.Background(), time.Hour) {
+		t.Error("no deadline should always fit")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if !fitsDeadline(ctx, time.Second) || fitsDeadline(ctx, time.Minute) {
+		t.Error("fitsDeadline does not compare against the remaining time")
+	}
+}
+
+func TestRateLimitedPastDeadline(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("slow down"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Email: "ops@example.com", APIToken: "secret", V5BaseURL: srv.URL, V6BaseURL: srv.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := c.V5(ctx, "POST", "/query/topXdata", map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "API error 429") {
+		t.Fatalf("err = %v, want the 429", err)
+	}
+	// The capped 60s wait does not fit the 30s deadline: fail at once
+	if n := calls.Load(); n != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("%d calls in %v, want 1 without waiting", n, time.Since(start))
+	}
+	if len(c.querySlots) != 0 {
+		t.Errorf("%d query slots still held", len(c.querySlots))
+	}
+}
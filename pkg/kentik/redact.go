@@ -0,0 +1,50 @@
+package kentik
+
+import (
+	"regexp"
+	"strings"
+)
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// maxErrorBody bounds how much of an error response body is quoted in errors.
+const maxErrorBody = 512
+
+// MaskEmail keeps the first character and the domain of an address, e.g.
+// "n***@example.com".
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// Redact masks the given secrets and every email address in s.
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if len(secret) >= 4 {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
+
+// redact masks the client's credentials, including recently replaced
+// tokens, and email addresses in s.
+func (c *Client) redact(s string) string {
+	c.credMu.RLock()
+	secrets := append([]string{c.apiToken, c.email}, c.oldTokens...)
+	c.credMu.RUnlock()
+	return Redact(s, secrets...)
+}
+
+// errorBody renders an error response body for an error message: redacted
+// and truncated.
+func (c *Client) errorBody(body []byte) string {
+	s := c.redact(strings.TrimSpace(string(body)))
+	if len(s) > maxErrorBody {
+		s = s[:maxErrorBody] + "… (truncated)"
+	}
+	return s
+}
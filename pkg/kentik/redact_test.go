@@ -0,0 +1,63 @@
+package kentik
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+		secrets  []string
+	}{
+		{"token abcd1234 rejected", "token [REDACTED] rejected", []string{"abcd1234"}},
+		{"short abc kept", "short abc kept", []string{"abc"}},
+		{"user noc@example.com", "user n***@example.com", nil},
+		{"no secrets", "no secrets", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in, tt.secrets...); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactAfterRotation(t *testing.T) {
+	c := NewClient(Config{Email: "ops@example.com", APIToken: "token-0"})
+	tokens := []string{"token-0"}
+	for i := 1; i <= maxOldTokens+2; i++ {
+		tok := "token-" + string(rune('0'+i))
+		c.SetAPIToken(tok)
+		tokens = append(tokens, tok)
+	}
+	c.SetAPIToken(tokens[len(tokens)-1]) // same token: nothing to retire
+
+	in := strings.Join(tokens, " ") + " ops@example.com"
+	got := strings.Fields(c.redact(in))
+	// The current token and the last maxOldTokens replaced ones are masked;
+	// older ones have been dropped
+	for i, tok := range tokens {
+		masked := got[i] == "[REDACTED]"
+		if want := i >= len(tokens)-1-maxOldTokens; masked != want {
+			t.Errorf("%s masked = %v, want %v", tok, masked, want)
+		}
+	}
+	if got[len(got)-1] != "[REDACTED]" {
+		t.Errorf("email not masked: %s", got[len(got)-1])
+	}
+	if n := len(c.oldTokens); n != maxOldTokens {
+		t.Errorf("kept %d old tokens, want %d", n, maxOldTokens)
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	c := NewClient(Config{Email: "ops@example.com", APIToken: "secret-token"})
+	body := []byte(`{"error":"bad token secret-token"}` + strings.Repeat("x", maxErrorBody))
+	got := c.errorBody(body)
+	if strings.Contains(got, "secret-token") {
+		t.Errorf("token in error body: %s", got)
+	}
+	if !strings.HasSuffix(got, "… (truncated)") || len(got) > maxErrorBody+len("… (truncated)") {
+		t.Errorf("error body not truncated: %d bytes", len(got))
+	}
+}
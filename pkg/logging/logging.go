@@ -0,0 +1,141 @@
+// Package logging sets up the server's structured logger and carries the
+// per-tool-call trace ID through contexts.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config selects log verbosity, format and destination.
+type Config struct {
+	Level  string // debug, info (default), warn, error or off
+	Format string // text (default) or json
+	File   string // log file; empty logs to stderr
+}
+
+// New returns a logger for cfg. Every message and string attribute is
+// passed through redact before it is written. The returned closer closes
+// the log file, if any.
+func New(cfg Config, redact func(string) string) (*slog.Logger, io.Closer, error) {
+	if strings.EqualFold(cfg.Level, "off") {
+		return Discard(), io.NopCloser(nil), nil
+	}
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer = io.NopCloser(nil)
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening log file: %v", err)
+		}
+		out, closer = f, f
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(out, opts)
+	case "json":
+		h = slog.NewJSONHandler(out, opts)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q (text or json)", cfg.Format)
+	}
+	if redact != nil {
+		h = &redactingHandler{Handler: h, redact: redact}
+	}
+	return slog.New(h), closer, nil
+}
+
+// ParseLevel parses a level name; empty means info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (debug, info, warn, error, off)", s)
+}
+
+// Discard returns a logger that drops everything.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+type traceKey struct{}
+
+// NewTraceID returns a random 16-hex-digit trace ID.
+func NewTraceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithTraceID returns a context carrying the trace ID.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceKey{}, id)
+}
+
+// TraceID returns the context's trace ID, or "".
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceKey{}).(string)
+	return id
+}
+
+// redactingHandler redacts messages and string attributes.
+type redactingHandler struct {
+	slog.Handler
+	redact func(string) string
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h *redactingHandler) redactAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redact(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redacted := make([]any, len(attrs))
+		for i, ga := range attrs {
+			redacted[i] = h.redactAttr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	}
+	return a
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactAttr(a)
+	}
+	return &redactingHandler{Handler: h.Handler.WithAttrs(redacted), redact: h.redact}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{Handler: h.Handler.WithGroup(name), redact: h.redact}
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactingHandler(t *testing.T) {
+	redact := func(s string) string { return strings.ReplaceAll(s, "s3cret", "[REDACTED]") }
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+	}{
+		{"message", func(l *slog.Logger) { l.Info("token s3cret rejected") }},
+		{"string attribute", func(l *slog.Logger) { l.Info("request", "header", "X-CH-Auth-API-Token: s3cret") }},
+		{"group", func(l *slog.Logger) { l.Info("request", slog.Group("auth", "token", "s3cret")) }},
+		{"With", func(l *slog.Logger) { l.With("token", "s3cret").Info("request") }},
+		{"WithGroup", func(l *slog.Logger) { l.WithGroup("auth").Info("request", "token", "s3cret") }},
+	}
+	for _, format := range []string{"text", "json"} {
+		for _, tt := range tests {
+			var buf bytes.Buffer
+			opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+			var h slog.Handler = slog.NewTextHandler(&buf, opts)
+			if format == "json" {
+				h = slog.NewJSONHandler(&buf, opts)
+			}
+			tt.log(slog.New(&redactingHandler{Handler: h, redact: redact}))
+			if out := buf.String(); strings.Contains(out, "s3cret") || !strings.Contains(out, "[REDACTED]") {
+				t.Errorf("%s/%s: secret not redacted: %s", format, tt.name, out)
+			}
+		}
+	}
+}
+
+func TestRedactingHandlerKeepsOtherValues(t *testing.T) {
+	var buf bytes.Buffer
+	h := &redactingHandler{Handler: slog.NewTextHandler(&buf, nil), redact: strings.ToUpper}
+	slog.New(h).Info("done", "status", 429, "path", "/devices")
+	if out := buf.String(); !strings.Contains(out, "status=429") || !strings.Contains(out, "path=/DEVICES") || !strings.Contains(out, "msg=DONE") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+		ok   bool
+	}{
+		{"", slog.LevelInfo, true},
+		{"DEBUG", slog.LevelDebug, true},
+		{"warning", slog.LevelWarn, true},
+		{"error", slog.LevelError, true},
+		{"verbose", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	id := NewTraceID()
+	if len(id) != 16 {
+		t.Errorf("NewTraceID() = %q, want 16 hex digits", id)
+	}
+	if got := TraceID(WithTraceID(context.Background(), id)); got != id {
+		t.Errorf("TraceID = %q, want %q", got, id)
+	}
+	if got := TraceID(context.Background()); got != "" {
+		t.Errorf("TraceID without ID = %q", got)
+	}
+}
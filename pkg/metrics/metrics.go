@@ -0,0 +1,223 @@
+// Package metrics keeps the server's Prometheus metrics and serves them in
+// the text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics exported by the server.
+var (
+	ToolCalls = NewCounter("kentik_mcp_tool_calls_total",
+		"Tool invocations by tool and outcome (ok, error, failed).", "tool", "outcome")
+	ToolDuration = NewHistogram("kentik_mcp_tool_duration_seconds",
+		"Tool call duration.", DefaultBuckets, "tool")
+	UpstreamRequests = NewCounter("kentik_mcp_upstream_requests_total",
+		"Kentik API requests by account, endpoint class and HTTP status (\"error\" when no response).", "account", "endpoint", "status")
+	UpstreamDuration = NewHistogram("kentik_mcp_upstream_request_duration_seconds",
+		"Kentik API request latency including retries.", DefaultBuckets, "account", "endpoint")
+	UpstreamRetries = NewCounter("kentik_mcp_upstream_retries_total",
+		"Kentik API requests retried after HTTP 429.", "account", "endpoint")
+	QuerySlotWait = NewHistogram("kentik_mcp_query_slot_wait_seconds",
+		"Time Query API requests waited for one of the concurrent query slots.",
+		[]float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}, "account")
+	CacheLookups = NewCounter("kentik_mcp_cache_lookups_total",
+		"Inventory cache lookups by path and result (hit, miss).", "path", "result")
+	AdvisorPollDuration = NewHistogram("kentik_mcp_ai_advisor_wait_seconds",
+		"Time from AI Advisor question to final status, by status (completed, failed, timeout).",
+		[]float64{5, 10, 20, 30, 45, 60, 90, 120, 180, 300}, "status")
+)
+
+// DefaultBuckets suit request latencies in seconds.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+type collector interface {
+	write(w io.Writer)
+}
+
+func register(c collector) {
+	registryMu.Lock()
+	registry = append(registry, c)
+	registryMu.Unlock()
+}
+
+// Handler serves every registered metric.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteTo(w)
+	})
+}
+
+// WriteTo writes every registered metric in the text exposition format.
+func WriteTo(w io.Writer) {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Counter is a counter with labels.
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v to the series with the given label values.
+func (c *Counter) Add(v float64, values ...string) {
+	key := labelString(c.labels, values)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+}
+
+// Histogram is a histogram with labels.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given upper bounds.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe records v in the series with the given label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := labelString(h.labels, values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// ObserveSince records the seconds elapsed since start.
+func (h *Histogram) ObserveSince(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, b := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, "le", formatFloat(b)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, s.count)
+	}
+}
+
+// labelString renders {a="x",b="y"}; missing values are empty.
+func labelString(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		parts[i] = n + `="` + escape(v) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withLabel appends one label to a rendered label set.
+func withLabel(key, name, value string) string {
+	l := name + `="` + value + `"`
+	if key == "" {
+		return "{" + l + "}"
+	}
+	return key[:len(key)-1] + "," + l + "}"
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
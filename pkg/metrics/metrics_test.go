@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterExposition(t *testing.T) {
+	c := NewCounter("test_calls_total", "Calls.", "tool", "outcome")
+	c.Inc("kentik_query", "ok")
+	c.Inc("kentik_query", "ok")
+	c.Add(0.5, "say \"hi\"\n", `a\b`)
+	c.Inc("kentik_devices") // missing label value
+
+	var sb strings.Builder
+	c.write(&sb)
+	want := `# HELP test_calls_total Calls.
+# TYPE test_calls_total counter
+test_calls_total{tool="kentik_devices",outcome=""} 1
+test_calls_total{tool="kentik_query",outcome="ok"} 2
+test_calls_total{tool="say \"hi\"\n",outcome="a\\b"} 0.5
+`
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "Duration.", []float64{0.1, 1}, "tool")
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v, "kentik_query")
+	}
+
+	var sb strings.Builder
+	h.write(&sb)
+	want := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{tool="kentik_query",le="0.1"} 2
+test_duration_seconds_bucket{tool="kentik_query",le="1"} 3
+test_duration_seconds_bucket{tool="kentik_query",le="+Inf"} 4
+test_duration_seconds_sum{tool="kentik_query"} 3.65
+test_duration_seconds_count{tool="kentik_query"} 4
+`
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ToolCalls.Inc("kentik_list_devices", "ok")
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	for _, want := range []string{
+		`kentik_mcp_tool_calls_total{tool="kentik_list_devices",outcome="ok"} 1`,
+		"# TYPE kentik_mcp_upstream_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("exposition lacks %q", want)
+		}
+	}
+}
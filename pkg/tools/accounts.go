@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Account is a named Kentik account (company) the server can query.
+type Account struct {
+	Name   string
+	Email  string
+	Region string
+	Client *kentik.Client
+}
+
+// registerMultiAccountTools registers each Kentik tool once, with an
+// account parameter that routes the call to that account's handler. The
+// handlers are built per account by registering the tools on a scratch
+// server for each client.
+func registerMultiAccountTools(s *server.MCPServer, accounts []Account, opts Options) {
+	handlers := make(map[string]map[string]server.ToolHandlerFunc)
+	var defs []mcp.Tool
+	for i, a := range accounts {
+		scratch := server.NewMCPServer(a.Name, "")
+		registerKentikTools(scratch, a.Client, opts)
+		for name, st := range scratch.ListTools() {
+			if handlers[name] == nil {
+				handlers[name] = make(map[string]server.ToolHandlerFunc)
+			}
+			handlers[name][strings.ToLower(a.Name)] = st.Handler
+			if i == 0 {
+				defs = append(defs, st.Tool)
+			}
+		}
+	}
+
+	names := accountNames(accounts)
+	for _, tool := range defs {
+		mcp.WithString("account",
+			mcp.Description(fmt.Sprintf("Kentik account to query: %s. Default: %s", strings.Join(names, ", "), accounts[0].Name)),
+		)(&tool)
+		s.AddTool(tool, makeAccountDispatcher(handlers[tool.Name], accounts[0].Name, names))
+	}
+}
+
+// makeAccountDispatcher routes a call to the handler of the requested account.
+func makeAccountDispatcher(byAccount map[string]server.ToolHandlerFunc, primary string, names []string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		account := primary
+		if a, err := request.RequireString("account"); err == nil && a != "" {
+			account = a
+		}
+		handler, ok := byAccount[strings.ToLower(account)]
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown account %q. Configured accounts: %s", account, strings.Join(names, ", "))), nil
+		}
+		return handler(ctx, request)
+	}
+}
+
+func accountNames(accounts []Account) []string {
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		names[i] = a.Name
+	}
+	return names
+}
+
+func registerAccountTools(s *server.MCPServer, accounts []Account) {
+	listAccounts := mcp.NewTool("kentik_list_accounts",
+		mcp.WithDescription("List the Kentik accounts this server is configured for. Pass an account name as the account parameter of other tools to query that account."),
+		localTool(true, false),
+	)
+	s.AddTool(listAccounts, makeListAccountsHandler(accounts))
+}
+
+func makeListAccountsHandler(accounts []Account) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## Kentik Accounts (%d)\n\n", len(accounts)))
+		sb.WriteString(fmt.Sprintf("| %-20s | %-35s | %-6s | %-7s |\n", "Account", "Email", "Region", "Primary"))
+		sb.WriteString("|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 37) + "|" + strings.Repeat("-", 8) + "|" + strings.Repeat("-", 9) + "|\n")
+		for i, a := range accounts {
+			primary := ""
+			if i == 0 {
+				primary = "yes"
+			}
+			region := strings.ToUpper(a.Region)
+			if region == "" {
+				region = "US"
+			}
+			sb.WriteString(fmt.Sprintf("| %-20s | %-35s | %-6s | %-7s |\n", a.Name, kentik.MaskEmail(a.Email), region, primary))
+		}
+		if len(accounts) == 1 {
+			sb.WriteString("\nOnly one account is configured; tools take no account parameter.\n")
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerActionTools registers tools that change state in Kentik. They are
+// only registered when the server runs with write mode enabled.
+func registerActionTools(s *server.MCPServer, client *kentik.Client) {
+	ackAlarm := mcp.NewTool("kentik_ack_alarm",
+		mcp.WithDescription("Acknowledge an active alarm. WRITE ACTION: requires confirm to repeat the alarm ID, and is recorded in the local audit log."),
+		writeTool(false, true),
+		mcp.WithString("alarm_id",
+			mcp.Required(),
+			mcp.Description("Alarm ID from kentik_list_alerts"),
+		),
+		mcp.WithString("confirm",
+			mcp.Required(),
+			mcp.Description("Must equal alarm_id to confirm the action"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(ackAlarm, makeAlarmActionHandler(client, "ack"))
+
+	clearAlarm := mcp.NewTool("kentik_clear_alarm",
+		mcp.WithDescription("Clear an active alarm. WRITE ACTION: requires confirm to repeat the alarm ID, and is recorded in the local audit log."),
+		writeTool(true, true),
+		mcp.WithString("alarm_id",
+			mcp.Required(),
+			mcp.Description("Alarm ID from kentik_list_alerts"),
+		),
+		mcp.WithString("confirm",
+			mcp.Required(),
+			mcp.Description("Must equal alarm_id to confirm the action"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(clearAlarm, makeAlarmActionHandler(client, "clear"))
+
+	startMitigation := mcp.NewTool("kentik_start_mitigation",
+		mcp.WithDescription("Start a manual mitigation (e.g. RTBH or flowspec) for an IP or prefix on a mitigation platform. WRITE ACTION: requires confirm to repeat the IP/prefix, and is recorded in the local audit log."),
+		writeTool(true, false),
+		mcp.WithString("ip_cidr",
+			mcp.Required(),
+			mcp.Description("IP address or CIDR prefix to mitigate"),
+		),
+		mcp.WithNumber("platform_id",
+			mcp.Required(),
+			mcp.Description("Mitigation platform ID"),
+		),
+		mcp.WithNumber("method_id",
+			mcp.Required(),
+			mcp.Description("Mitigation method ID on that platform"),
+		),
+		mcp.WithNumber("minutes_before_auto_stop",
+			mcp.Description("Stop the mitigation automatically after this many minutes. Default: 60; 0 keeps it running until stopped"),
+		),
+		mcp.WithString("confirm",
+			mcp.Required(),
+			mcp.Description("Must equal ip_cidr to confirm the action"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Comment stored with the mitigation and in the audit log"),
+		),
+	)
+	s.AddTool(startMitigation, makeStartMitigationHandler(client))
+
+	stopMitigation := mcp.NewTool("kentik_stop_mitigation",
+		mcp.WithDescription("Stop a running manual mitigation. WRITE ACTION: requires confirm to repeat the mitigation ID, and is recorded in the local audit log."),
+		writeTool(true, true),
+		mcp.WithString("mitigation_id",
+			mcp.Required(),
+			mcp.Description("ID of the mitigation to stop"),
+		),
+		mcp.WithString("confirm",
+			mcp.Required(),
+			mcp.Description("Must equal mitigation_id to confirm the action"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(stopMitigation, makeStopMitigationHandler(client))
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time    string                 `json:"time"`
+	Tool    string                 `json:"tool"`
+	Account string                 `json:"account,omitempty"`
+	Target  string                 `json:"target"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Outcome string                 `json:"outcome"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// auditFile returns the audit log location, next to the contexts file.
+func (o Options) auditFile() string {
+	return filepath.Join(filepath.Dir(o.contextFile()), ".kentik-mcp-audit.jsonl")
+}
+
+// appendAudit appends an entry to the audit log at path.
+func appendAudit(path string, entry auditEntry) error {
+	entry.Time = time.Now().UTC().Format(time.RFC3339)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// runAction checks the confirmation, performs the action and records the
+// outcome against the client's account. The action is refused if the audit
+// log cannot be written.
+func runAction(ctx context.Context, client *kentik.Client, tool, target, confirm string, params map[string]interface{}, action func() (json.RawMessage, error)) (*mcp.CallToolResult, error) {
+	path := optionsFrom(ctx).auditFile()
+	entry := auditEntry{Tool: tool, Account: client.Name(), Target: target, Params: params}
+	if strings.TrimSpace(confirm) != target {
+		entry.Outcome = "rejected"
+		entry.Error = "confirmation mismatch"
+		if err := appendAudit(path, entry); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to write audit log: %v", err)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Not confirmed: confirm must be exactly %q. Nothing was changed.", target)), nil
+	}
+
+	entry.Outcome = "attempted"
+	if err := appendAudit(path, entry); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Refusing to act without an audit log: %v", err)), nil
+	}
+
+	data, err := action()
+	entry.Outcome = "ok"
+	if err != nil {
+		entry.Outcome = "error"
+		entry.Error = err.Error()
+	}
+	auditErr := appendAudit(path, entry)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to %s %s: %v", strings.TrimPrefix(tool, "kentik_"), target, err)), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Done: %s %s.\n", tool, target))
+	if auditErr != nil {
+		sb.WriteString(fmt.Sprintf("\n⚠ The action succeeded but its result could not be written to the audit log: %v\n", auditErr))
+	} else {
+		sb.WriteString(fmt.Sprintf("Recorded in %s.\n", path))
+	}
+	if len(data) > 0 {
+		sb.WriteString("\n```json\n" + formatJSON(data) + "\n```\n")
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func makeAlarmActionHandler(client *kentik.Client, action string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		alarmID, err := request.RequireString("alarm_id")
+		if f, ferr := request.RequireFloat("alarm_id"); err != nil && ferr == nil {
+			alarmID, err = strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		if err != nil || strings.TrimSpace(alarmID) == "" {
+			return mcp.NewToolResultError("alarm_id is required"), nil
+		}
+		alarmID = strings.TrimSpace(alarmID)
+		id, err := strconv.ParseInt(alarmID, 10, 64)
+		if err != nil || id <= 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid alarm_id '%s': must be a positive integer", alarmID)), nil
+		}
+		confirm, _ := request.RequireString("confirm")
+		comment, _ := request.RequireString("comment")
+
+		params := map[string]interface{}{"comment": comment}
+		return runAction(ctx, client, "kentik_"+action+"_alarm", alarmID, confirm, params, func() (json.RawMessage, error) {
+			return client.V5(ctx, "POST", "/alerts-active/"+action, map[string]interface{}{"alarm_id": id})
+		})
+	}
+}
+
+func makeStartMitigationHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ipCidr, err := request.RequireString("ip_cidr")
+		if err != nil || strings.TrimSpace(ipCidr) == "" {
+			return mcp.NewToolResultError("ip_cidr is required"), nil
+		}
+		ipCidr = strings.TrimSpace(ipCidr)
+		if _, _, err := net.ParseCIDR(ipCidr); err != nil && net.ParseIP(ipCidr) == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid ip_cidr '%s': must be an IP address or CIDR prefix", ipCidr)), nil
+		}
+		platformID, err := request.RequireFloat("platform_id")
+		if err != nil || platformID <= 0 {
+			return mcp.NewToolResultError("platform_id is required and must be a positive ID"), nil
+		}
+		methodID, err := request.RequireFloat("method_id")
+		if err != nil || methodID <= 0 {
+			return mcp.NewToolResultError("method_id is required and must be a positive ID"), nil
+		}
+		autoStop := 60.0
+		if v, err := request.RequireFloat("minutes_before_auto_stop"); err == nil && v >= 0 {
+			autoStop = v
+		}
+		confirm, _ := request.RequireString("confirm")
+		comment, _ := request.RequireString("comment")
+
+		body := map[string]interface{}{
+			"ipCidr":                ipCidr,
+			"platformID":            int(platformID),
+			"methodID":              int(methodID),
+			"minutesBeforeAutoStop": int(autoStop),
+			"comment":               comment,
+		}
+		return runAction(ctx, client, "kentik_start_mitigation", ipCidr, confirm, body, func() (json.RawMessage, error) {
+			return client.V5(ctx, "POST", "/alerts/manual-mitigate", body)
+		})
+	}
+}
+
+func makeStopMitigationHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		mitigationID, err := request.RequireString("mitigation_id")
+		if f, ferr := request.RequireFloat("mitigation_id"); err != nil && ferr == nil {
+			mitigationID, err = strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		if err != nil || strings.TrimSpace(mitigationID) == "" {
+			return mcp.NewToolResultError("mitigation_id is required"), nil
+		}
+		mitigationID = strings.TrimSpace(mitigationID)
+		id, err := strconv.ParseInt(mitigationID, 10, 64)
+		if err != nil || id <= 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid mitigation_id '%s': must be a positive integer", mitigationID)), nil
+		}
+		confirm, _ := request.RequireString("confirm")
+		comment, _ := request.RequireString("comment")
+
+		params := map[string]interface{}{"comment": comment}
+		return runAction(ctx, client, "kentik_stop_mitigation", mitigationID, confirm, params, func() (json.RawMessage, error) {
+			return client.V5(ctx, "POST", "/alerts/manual-mitigate/stop", map[string]interface{}{"mitigation_id": id})
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestMitigationArgsRejected(t *testing.T) {
+	start := makeStartMitigationHandler(nil)
+	stop := makeStopMitigationHandler(nil)
+	tests := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]any
+	}{
+		{"bad ip", start, map[string]any{"ip_cidr": "10.0.0.300", "platform_id": 1, "method_id": 2}},
+		{"bad prefix", start, map[string]any{"ip_cidr": "10.0.0.0/33", "platform_id": 1, "method_id": 2}},
+		{"zero platform", start, map[string]any{"ip_cidr": "10.0.0.0/24", "platform_id": 0, "method_id": 2}},
+		{"negative method", start, map[string]any{"ip_cidr": "2001:db8::1", "platform_id": 1, "method_id": -2}},
+		{"non-numeric mitigation", stop, map[string]any{"mitigation_id": "abc"}},
+		{"zero mitigation", stop, map[string]any{"mitigation_id": "0"}},
+	}
+	for _, tt := range tests {
+		res, err := tt.handler(context.Background(), toolRequest(tt.args))
+		if err != nil || !res.IsError {
+			t.Errorf("%s: got %v, %v; want a tool error", tt.name, res, err)
+		}
+	}
+}
@@ -4,16 +4,278 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/awlx/kentik-mcp/pkg/metrics"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerAIAdvisorTools(s *server.MCPServer, client *kentik.Client) {
+// Polling starts fast and backs off; Kentik allows 60 polls per minute, so
+// polls of one account are never closer than advisorMinPoll.
+const (
+	advisorMinPoll     = time.Second
+	advisorMaxPoll     = 10 * time.Second
+	advisorWaitCeiling = 10 * time.Minute
+)
+
+// advisorPacer spaces the polls of all AI Advisor calls of one account.
+type advisorPacer struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait sleeps for d, or longer if another call polled recently.
+func (p *advisorPacer) wait(ctx context.Context, d time.Duration) error {
+	p.mu.Lock()
+	at := time.Now().Add(d)
+	if at.Before(p.next) {
+		at = p.next
+	}
+	p.next = at.Add(advisorMinPoll)
+	p.mu.Unlock()
+
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+type advisorMessage struct {
+	ID           string          `json:"id"`
+	Status       string          `json:"status"`
+	Prompt       string          `json:"prompt"`
+	FinalAnswer  string          `json:"finalAnswer"`
+	Reasoning    json.RawMessage `json:"reasoning"`
+	ErrorMessage string          `json:"errorMessage"`
+	CreatedAt    string          `json:"createdAt"`
+	// Extra holds the other non-empty fields Kentik returns, such as data
+	// references.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// advisorMessageFields are the fields decoded into advisorMessage.
+var advisorMessageFields = map[string]bool{
+	"id": true, "status": true, "prompt": true, "finalAnswer": true, "reasoning": true,
+	"errorMessage": true, "createdAt": true, "updatedAt": true, "cdate": true, "edate": true,
+}
+
+func (m *advisorMessage) UnmarshalJSON(b []byte) error {
+	type plain advisorMessage
+	if err := json.Unmarshal(b, (*plain)(m)); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	for k, v := range all {
+		switch strings.TrimSpace(string(v)) {
+		case "", "null", `""`, "[]", "{}":
+			continue
+		}
+		if !advisorMessageFields[k] {
+			if m.Extra == nil {
+				m.Extra = make(map[string]json.RawMessage)
+			}
+			m.Extra[k] = v
+		}
+	}
+	return nil
+}
+
+// reasoning returns the reasoning as text; structured reasoning is
+// rendered as indented JSON.
+func (m advisorMessage) reasoning() string {
+	if len(m.Reasoning) == 0 || string(m.Reasoning) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(m.Reasoning, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	return "```json\n" + formatJSON(m.Reasoning) + "\n```"
+}
+
+// details renders the reasoning and the extra fields of the message.
+func (m advisorMessage) details() string {
+	var sb strings.Builder
+	if r := m.reasoning(); r != "" {
+		sb.WriteString("\n\n#### Reasoning\n\n" + r)
+	}
+	if len(m.Extra) > 0 {
+		keys := make([]string, 0, len(m.Extra))
+		for k := range m.Extra {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString("\n\n#### Data References\n")
+		for _, k := range keys {
+			sb.WriteString(fmt.Sprintf("\n**%s**\n\n```json\n%s\n```\n", k, formatJSON(m.Extra[k])))
+		}
+	}
+	return sb.String()
+}
+
+type advisorSession struct {
+	ID        string           `json:"id"`
+	Status    string           `json:"status"`
+	CreatedAt string           `json:"createdAt"`
+	Cdate     string           `json:"cdate"`
+	Messages  []advisorMessage `json:"messages"`
+}
+
+// created returns the session's creation time as reported by Kentik,
+// falling back to its first message.
+func (s *advisorSession) created() string {
+	switch {
+	case s.CreatedAt != "":
+		return s.CreatedAt
+	case s.Cdate != "":
+		return s.Cdate
+	case len(s.Messages) > 0:
+		return s.Messages[0].CreatedAt
+	}
+	return ""
+}
+
+func (s *advisorSession) done() bool {
+	return s.Status == "SESSION_STATUS_COMPLETED" || s.Status == "SESSION_STATUS_FAILED"
+}
+
+func (s *advisorSession) last() advisorMessage {
+	if len(s.Messages) == 0 {
+		return advisorMessage{}
+	}
+	return s.Messages[len(s.Messages)-1]
+}
+
+// advisorStatus renders "SESSION_STATUS_IN_PROGRESS" as "in progress".
+func advisorStatus(status string) string {
+	s := strings.ToLower(strings.TrimPrefix(status, "SESSION_STATUS_"))
+	if s == "" {
+		return "unknown"
+	}
+	return strings.ReplaceAll(s, "_", " ")
+}
+
+// advisorWait returns how long kentik_ai_advisor waits for an answer.
+func (o Options) advisorWait() time.Duration {
+	if o.AdvisorMaxWait > 0 {
+		return o.AdvisorMaxWait
+	}
+	return 90 * time.Second
+}
+
+func getAdvisorSession(ctx context.Context, client *kentik.Client, id string) (*advisorSession, error) {
+	data, err := client.V6(ctx, "GET", fmt.Sprintf("/ai_advisor/v202511/chat/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var sess advisorSession
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("parse session: %w", err)
+	}
+	return &sess, nil
+}
+
+// waitAdvisorSession polls the session until it completes, fails or
+// maxWait passes, backing off from advisorMinPoll to advisorMaxPoll. It
+// polls at least once and reports every poll to progress.
+func waitAdvisorSession(ctx context.Context, client *kentik.Client, pacer *advisorPacer, id string, maxWait time.Duration,
+	progress func(sess *advisorSession, elapsed time.Duration)) (*advisorSession, error) {
+	started := time.Now()
+	deadline := started.Add(maxWait)
+	interval := advisorMinPoll
+	var sess *advisorSession
+	for {
+		if err := pacer.wait(ctx, max(min(interval, time.Until(deadline)), 0)); err != nil {
+			return sess, err
+		}
+		s, err := getAdvisorSession(ctx, client, id)
+		if err != nil {
+			return sess, err
+		}
+		sess = s
+		progress(sess, time.Since(started))
+		if sess.done() || !time.Now().Before(deadline) {
+			return sess, nil
+		}
+		interval = min(interval*3/2, advisorMaxPoll)
+	}
+}
+
+// progressNotifier returns a function that sends notifications/progress
+// for request, or a no-op when the client did not pass a progress token.
+func progressNotifier(ctx context.Context, request mcp.CallToolRequest) func(progress, total float64, message string) {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil || request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return func(float64, float64, string) {}
+	}
+	token := request.Params.Meta.ProgressToken
+	return func(progress, total float64, message string) {
+		_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+			"total":         total,
+			"message":       message,
+		})
+	}
+}
+
+// advisorResult renders a finished or still running session; with
+// details it adds the reasoning and data references of the answer.
+func advisorResult(sess *advisorSession, waited time.Duration, details bool) *mcp.CallToolResult {
+	switch sess.Status {
+	case "SESSION_STATUS_COMPLETED":
+		if len(sess.Messages) == 0 {
+			data, _ := json.Marshal(sess)
+			return mcp.NewToolResultText(formatJSON(data))
+		}
+		text := fmt.Sprintf("**AI Advisor Response** (session: %s)\n\n%s", sess.ID, sess.last().FinalAnswer)
+		if details {
+			text += sess.last().details()
+		}
+		return mcp.NewToolResultText(text)
+	case "SESSION_STATUS_FAILED":
+		errMsg := "Unknown error"
+		if m := sess.last(); m.ErrorMessage != "" {
+			errMsg = m.ErrorMessage
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("AI Advisor failed: %s", errMsg))
+	}
+	return mcp.NewToolResultText(fmt.Sprintf(
+		"AI Advisor is still working (status: %s after %s). Session ID: %s — fetch the answer later with kentik_ai_advisor_status instead of asking again.",
+		advisorStatus(sess.Status), formatDuration(waited), sess.ID,
+	))
+}
+
+// appendText adds text to the first text content of result.
+func appendText(result *mcp.CallToolResult, text string) *mcp.CallToolResult {
+	if len(result.Content) > 0 {
+		if tc, ok := result.Content[0].(mcp.TextContent); ok {
+			tc.Text += text
+			result.Content[0] = tc
+		}
+	}
+	return result
+}
+
+func registerAIAdvisorTools(s *server.MCPServer, client *kentik.Client, opts Options) {
+	pacer := &advisorPacer{}
+
 	askAdvisor := mcp.NewTool("kentik_ai_advisor",
-		mcp.WithDescription("Ask Kentik's AI Advisor a natural language question about your network. The AI analyzes your Kentik data and returns insights. Examples: 'How are my devices doing?', 'Show me top talkers in the last hour', 'What about interface utilization?'. This is an async operation — the tool polls for completion automatically."),
+		mcp.WithDescription("Ask Kentik's AI Advisor a natural language question about your network. The AI analyzes your Kentik data and returns insights. Examples: 'How are my devices doing?', 'Show me top talkers in the last hour', 'What about interface utilization?'. This is an async operation — the tool polls for completion, sends progress notifications while waiting, and returns the session ID if the answer is not ready in time."),
+		readOnlyTool(),
+		mcp.WithIdempotentHintAnnotation(false),
 		mcp.WithString("question",
 			mcp.Required(),
 			mcp.Description("Natural language question about your network to ask the AI Advisor"),
@@ -21,17 +283,63 @@ func registerAIAdvisorTools(s *server.MCPServer, client *kentik.Client) {
 		mcp.WithString("session_id",
 			mcp.Description("Optional existing session ID for follow-up questions. If provided, the question is added as a follow-up to the existing conversation."),
 		),
+		mcp.WithString("conversation",
+			mcp.Description("Name of a locally remembered conversation to continue (see kentik_list_ai_advisor_conversations), or a name for the new one. Every new session is remembered (except in read-only mode), named after its first question unless set."),
+		),
+		mcp.WithNumber("max_wait_seconds",
+			mcp.Description(fmt.Sprintf("How long to wait for the answer. 0 submits the question and returns the session ID at once. Default: %d", int(opts.advisorWait().Seconds()))),
+		),
+		mcp.WithBoolean("include_reasoning",
+			mcp.Description("Also return the AI Advisor's reasoning and any data references. Default: false"),
+		),
+	)
+	s.AddTool(askAdvisor, makeAIAdvisorHandler(client, pacer))
+
+	status := mcp.NewTool("kentik_ai_advisor_status",
+		mcp.WithDescription("Get the status or answer of an AI Advisor session started earlier with kentik_ai_advisor, without resubmitting the question."),
+		readOnlyTool(),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("The AI Advisor session ID"),
+		),
+		mcp.WithNumber("wait_seconds",
+			mcp.Description("Keep polling up to this long if the session is still running. Default: 0 (check once)"),
+		),
+		mcp.WithBoolean("include_reasoning",
+			mcp.Description("Also return the AI Advisor's reasoning and any data references. Default: false"),
+		),
 	)
-	s.AddTool(askAdvisor, makeAIAdvisorHandler(client))
+	s.AddTool(status, makeAIAdvisorStatusHandler(client, pacer))
+
+	registerAIAdvisorHistoryTools(s, client, pacer)
 }
 
-func makeAIAdvisorHandler(client *kentik.Client) server.ToolHandlerFunc {
+func makeAIAdvisorHandler(client *kentik.Client, pacer *advisorPacer) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		opts := optionsFrom(ctx)
 		question, err := request.RequireString("question")
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 		sessionID, _ := request.RequireString("session_id")
+		conversation, _ := request.RequireString("conversation")
+		conversation = strings.TrimSpace(conversation)
+		if conversation != "" && sessionID == "" {
+			c, err := lookupAdvisorConversation(opts.advisorStore(), conversation)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to load conversations: %v", err)), nil
+			}
+			if c != nil {
+				if c.Account != client.Name() {
+					return mcp.NewToolResultError(fmt.Sprintf("Conversation '%s' belongs to account %s.", c.Name, c.Account)), nil
+				}
+				sessionID = c.SessionID
+			}
+		}
+		maxWait := opts.advisorWait()
+		if v, err := request.RequireFloat("max_wait_seconds"); err == nil && v >= 0 {
+			maxWait = min(time.Duration(v*float64(time.Second)), advisorWaitCeiling)
+		}
 
 		var data json.RawMessage
 
@@ -40,12 +348,12 @@ func makeAIAdvisorHandler(client *kentik.Client) server.ToolHandlerFunc {
 				"id":     sessionID,
 				"prompt": question,
 			}
-			data, err = client.V6("PUT", "/ai_advisor/v202511/chat", body)
+			data, err = client.V6(ctx, "PUT", "/ai_advisor/v202511/chat", body)
 		} else {
 			body := map[string]interface{}{
 				"prompt": question,
 			}
-			data, err = client.V6("POST", "/ai_advisor/v202511/chat", body)
+			data, err = client.V6(ctx, "POST", "/ai_advisor/v202511/chat", body)
 		}
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to create AI Advisor session: %v", err)), nil
@@ -58,61 +366,76 @@ func makeAIAdvisorHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err := json.Unmarshal(data, &resp); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse AI Advisor response: %v", err)), nil
 		}
+		if resp.ID == "" {
+			resp.ID = sessionID
+		}
+		if resp.ID == "" {
+			return mcp.NewToolResultError("AI Advisor returned no session ID"), nil
+		}
 
-		// Poll for completion (max 90 seconds, 2-second intervals)
-		maxWait := 90 * time.Second
-		interval := 2 * time.Second
-		elapsed := time.Duration(0)
+		// The question was accepted; remembering it locally is best effort
+		// and skipped in read-only mode, which writes no local state
+		note := ""
+		if !opts.ReadOnly {
+			if name, err := recordAdvisorQuestion(opts.advisorStore(), client.Name(), resp.ID, conversation, question, sessionID != ""); err != nil {
+				note = fmt.Sprintf("\n\n*Not remembered locally: %v*", err)
+			} else {
+				note = fmt.Sprintf("\n\n*Conversation: %s — pass conversation '%s' to ask a follow-up.*", name, name)
+			}
+		}
 
-		for elapsed < maxWait {
-			time.Sleep(interval)
-			elapsed += interval
+		if maxWait == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf(
+				"AI Advisor question submitted (status: %s). Session ID: %s — fetch the answer with kentik_ai_advisor_status.%s",
+				advisorStatus(resp.Status), resp.ID, note,
+			)), nil
+		}
 
-			pollData, pollErr := client.V6("GET", fmt.Sprintf("/ai_advisor/v202511/chat/%s", resp.ID), nil)
-			if pollErr != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to poll AI Advisor: %v", pollErr)), nil
+		notify := progressNotifier(ctx, request)
+		started := time.Now()
+		sess, err := waitAdvisorSession(ctx, client, pacer, resp.ID, maxWait, func(sess *advisorSession, elapsed time.Duration) {
+			if sess.done() {
+				return
 			}
+			notify(elapsed.Seconds(), maxWait.Seconds(), fmt.Sprintf("AI Advisor session %s: %s (%s)", sess.ID, advisorStatus(sess.Status), formatDuration(elapsed)))
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to poll AI Advisor: %v. Session ID: %s — check it later with kentik_ai_advisor_status.", err, resp.ID)), nil
+		}
 
-			var pollResp struct {
-				ID       string `json:"id"`
-				Status   string `json:"status"`
-				Messages []struct {
-					ID           string `json:"id"`
-					Status       string `json:"status"`
-					Prompt       string `json:"prompt"`
-					FinalAnswer  string `json:"finalAnswer"`
-					Reasoning    string `json:"reasoning"`
-					ErrorMessage string `json:"errorMessage"`
-				} `json:"messages"`
-			}
-			if err := json.Unmarshal(pollData, &pollResp); err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to parse poll response: %v", err)), nil
-			}
+		switch sess.Status {
+		case "SESSION_STATUS_COMPLETED":
+			metrics.AdvisorPollDuration.ObserveSince(started, "completed")
+		case "SESSION_STATUS_FAILED":
+			metrics.AdvisorPollDuration.ObserveSince(started, "failed")
+		default:
+			metrics.AdvisorPollDuration.ObserveSince(started, "timeout")
+		}
+		return appendText(advisorResult(sess, time.Since(started), request.GetBool("include_reasoning", false)), note), nil
+	}
+}
 
-			switch pollResp.Status {
-			case "SESSION_STATUS_COMPLETED":
-				if len(pollResp.Messages) > 0 {
-					lastMsg := pollResp.Messages[len(pollResp.Messages)-1]
-					result := fmt.Sprintf("**AI Advisor Response** (session: %s)\n\n%s", pollResp.ID, lastMsg.FinalAnswer)
-					return mcp.NewToolResultText(result), nil
-				}
-				return mcp.NewToolResultText(formatJSON(pollData)), nil
-
-			case "SESSION_STATUS_FAILED":
-				errMsg := "Unknown error"
-				if len(pollResp.Messages) > 0 {
-					lastMsg := pollResp.Messages[len(pollResp.Messages)-1]
-					if lastMsg.ErrorMessage != "" {
-						errMsg = lastMsg.ErrorMessage
-					}
-				}
-				return mcp.NewToolResultError(fmt.Sprintf("AI Advisor failed: %s", errMsg)), nil
-			}
+func makeAIAdvisorStatusHandler(client *kentik.Client, pacer *advisorPacer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := request.RequireString("session_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		wait := time.Duration(0)
+		if v, err := request.RequireFloat("wait_seconds"); err == nil && v > 0 {
+			wait = min(time.Duration(v*float64(time.Second)), advisorWaitCeiling)
 		}
 
-		return mcp.NewToolResultError(fmt.Sprintf(
-			"AI Advisor timed out after %v. Session ID: %s — you can retry by passing this session_id.",
-			maxWait, resp.ID,
-		)), nil
+		notify := progressNotifier(ctx, request)
+		started := time.Now()
+		sess, err := waitAdvisorSession(ctx, client, pacer, sessionID, wait, func(sess *advisorSession, elapsed time.Duration) {
+			if wait > 0 && !sess.done() {
+				notify(elapsed.Seconds(), wait.Seconds(), fmt.Sprintf("AI Advisor session %s: %s (%s)", sess.ID, advisorStatus(sess.Status), formatDuration(elapsed)))
+			}
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get AI Advisor session: %v", err)), nil
+		}
+		return advisorResult(sess, time.Since(started), request.GetBool("include_reasoning", false)), nil
 	}
 }
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func listAdvisorSessions(ctx context.Context, client *kentik.Client, pacer *advisorPacer) ([]advisorSession, error) {
+	if err := pacer.wait(ctx, 0); err != nil {
+		return nil, err
+	}
+	data, err := client.V6(ctx, "GET", "/ai_advisor/v202511/chat", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Sessions []advisorSession `json:"sessions"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse sessions: %w", err)
+	}
+	return resp.Sessions, nil
+}
+
+// firstLine returns the first line of s, shortened to n runes.
+func firstLine(s string, n int) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return truncateLabel(s, n)
+}
+
+func registerAIAdvisorHistoryTools(s *server.MCPServer, client *kentik.Client, pacer *advisorPacer) {
+	listSessions := mcp.NewTool("kentik_list_ai_advisor_sessions",
+		mcp.WithDescription("List recent AI Advisor sessions, newest first: session ID, status, creation time, number of questions and the first question."),
+		readOnlyTool(),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of sessions. Default: 20"),
+		),
+	)
+	s.AddTool(listSessions, makeListAIAdvisorSessionsHandler(client, pacer))
+
+	getSession := mcp.NewTool("kentik_get_ai_advisor_session",
+		mcp.WithDescription("Full transcript of an AI Advisor session: every question with its answer, reasoning, data references and errors."),
+		readOnlyTool(),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("The AI Advisor session ID"),
+		),
+		mcp.WithString("format",
+			mcp.Description("'transcript' (default) or 'raw' for the session JSON"),
+		),
+	)
+	s.AddTool(getSession, makeGetAIAdvisorSessionHandler(client, pacer))
+}
+
+func makeListAIAdvisorSessionsHandler(client *kentik.Client, pacer *advisorPacer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		limit := 20
+		if v, err := request.RequireFloat("limit"); err == nil && v > 0 {
+			limit = int(v)
+		}
+		sessions, err := listAdvisorSessions(ctx, client, pacer)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list AI Advisor sessions: %v", err)), nil
+		}
+		if len(sessions) == 0 {
+			return mcp.NewToolResultText("No AI Advisor sessions found."), nil
+		}
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].created() > sessions[j].created()
+		})
+		total := len(sessions)
+		sessions = sessions[:min(limit, total)]
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## AI Advisor Sessions (%d of %d)\n\n", len(sessions), total))
+		sb.WriteString(fmt.Sprintf("| %-36s | %-11s | %-20s | %4s | %-50s |\n", "Session", "Status", "Created", "Qs", "First Question"))
+		sb.WriteString("|" + strings.Repeat("-", 38) + "|" + strings.Repeat("-", 13) + "|" + strings.Repeat("-", 22) +
+			"|" + strings.Repeat("-", 6) + "|" + strings.Repeat("-", 52) + "|\n")
+		for _, sess := range sessions {
+			prompt := ""
+			if len(sess.Messages) > 0 {
+				prompt = firstLine(sess.Messages[0].Prompt, 50)
+			}
+			sb.WriteString(fmt.Sprintf("| %-36s | %-11s | %-20s | %4d | %-50s |\n",
+				sess.ID, advisorStatus(sess.Status), truncateLabel(sess.created(), 20), len(sess.Messages), prompt))
+		}
+		sb.WriteString("\nUse kentik_get_ai_advisor_session for a transcript, or pass session_id to kentik_ai_advisor to ask a follow-up.\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+func makeGetAIAdvisorSessionHandler(client *kentik.Client, pacer *advisorPacer) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessionID, err := request.RequireString("session_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := pacer.wait(ctx, 0); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get AI Advisor session: %v", err)), nil
+		}
+		if format, _ := request.RequireString("format"); format == "raw" {
+			data, err := client.V6(ctx, "GET", fmt.Sprintf("/ai_advisor/v202511/chat/%s", sessionID), nil)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to get AI Advisor session: %v", err)), nil
+			}
+			return mcp.NewToolResultText(formatJSON(data)), nil
+		}
+		sess, err := getAdvisorSession(ctx, client, sessionID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get AI Advisor session: %v", err)), nil
+		}
+		return mcp.NewToolResultText(formatAdvisorTranscript(sess)), nil
+	}
+}
+
+func formatAdvisorTranscript(sess *advisorSession) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## AI Advisor Session %s\n\n", sess.ID))
+	sb.WriteString(fmt.Sprintf("Status: %s", advisorStatus(sess.Status)))
+	if c := sess.created(); c != "" {
+		sb.WriteString(", created " + c)
+	}
+	sb.WriteString(fmt.Sprintf(", %d questions\n", len(sess.Messages)))
+	for i, m := range sess.Messages {
+		sb.WriteString(fmt.Sprintf("\n### %d. %s\n\n", i+1, firstLine(m.Prompt, 80)))
+		if strings.Contains(strings.TrimSpace(m.Prompt), "\n") || len([]rune(m.Prompt)) > 80 {
+			sb.WriteString("> " + strings.ReplaceAll(strings.TrimSpace(m.Prompt), "\n", "\n> ") + "\n\n")
+		}
+		switch {
+		case m.ErrorMessage != "":
+			sb.WriteString("**Error:** " + m.ErrorMessage)
+		case m.FinalAnswer != "":
+			sb.WriteString(m.FinalAnswer)
+		default:
+			status := strings.ToLower(strings.TrimPrefix(m.Status, "MESSAGE_STATUS_"))
+			if status == "" {
+				status = "pending"
+			}
+			sb.WriteString(fmt.Sprintf("*No answer yet (%s).*", strings.ReplaceAll(status, "_", " ")))
+		}
+		sb.WriteString(m.details())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
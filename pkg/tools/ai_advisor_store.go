@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// advisorConversation is a named AI Advisor session kept locally so
+// follow-ups can refer to it by name.
+type advisorConversation struct {
+	Name        string `json:"name"`
+	SessionID   string `json:"session_id"`
+	Account     string `json:"account,omitempty"`
+	Created     string `json:"created"`
+	LastUsed    string `json:"last_used,omitempty"`
+	Questions   int    `json:"questions"`
+	FirstPrompt string `json:"first_prompt"`
+}
+
+type advisorConversationFile struct {
+	Conversations []advisorConversation `json:"conversations"`
+}
+
+// advisorStoreMu serializes read-modify-write cycles of the store file.
+var advisorStoreMu sync.Mutex
+
+// advisorStore returns the conversation store, next to the contexts file.
+func (o Options) advisorStore() string {
+	return filepath.Join(filepath.Dir(o.contextFile()), ".kentik-mcp-advisor-sessions.json")
+}
+
+func loadAdvisorConversations(path string) (*advisorConversationFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &advisorConversationFile{}, nil
+		}
+		return nil, err
+	}
+	var cf advisorConversationFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return nil, err
+	}
+	return &cf, nil
+}
+
+func saveAdvisorConversations(path string, cf *advisorConversationFile) error {
+	data, err := json.MarshalIndent(cf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// updateAdvisorConversations loads the store at path, applies fn and saves it.
+func updateAdvisorConversations(path string, fn func(cf *advisorConversationFile) error) error {
+	advisorStoreMu.Lock()
+	defer advisorStoreMu.Unlock()
+	cf, err := loadAdvisorConversations(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(cf); err != nil {
+		return err
+	}
+	return saveAdvisorConversations(path, cf)
+}
+
+// find returns the conversation with the given name, ignoring case.
+func (cf *advisorConversationFile) find(name string) *advisorConversation {
+	for i := range cf.Conversations {
+		if strings.EqualFold(cf.Conversations[i].Name, name) {
+			return &cf.Conversations[i]
+		}
+	}
+	return nil
+}
+
+func (cf *advisorConversationFile) bySession(account, sessionID string) *advisorConversation {
+	for i := range cf.Conversations {
+		if c := &cf.Conversations[i]; c.SessionID == sessionID && c.Account == account {
+			return c
+		}
+	}
+	return nil
+}
+
+// uniqueName derives a free conversation name from the first words of
+// prompt, e.g. "how-are-my-devices", adding "-2", "-3", ... if taken.
+func (cf *advisorConversationFile) uniqueName(prompt string) string {
+	words := strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	base := strings.Join(words[:min(len(words), 5)], "-")
+	if r := []rune(base); len(r) > 40 {
+		base = strings.TrimRight(string(r[:40]), "-")
+	}
+	if base == "" {
+		base = "conversation"
+	}
+	name := base
+	for i := 2; cf.find(name) != nil; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// lookupAdvisorConversation returns the stored conversation named name.
+func lookupAdvisorConversation(path, name string) (*advisorConversation, error) {
+	advisorStoreMu.Lock()
+	defer advisorStoreMu.Unlock()
+	cf, err := loadAdvisorConversations(path)
+	if err != nil {
+		return nil, err
+	}
+	return cf.find(name), nil
+}
+
+// recordAdvisorQuestion stores a question asked in sessionID under name,
+// creating the conversation if needed. An empty name keeps the session's
+// current name or derives one from the prompt. A follow-up to a session
+// not yet in the store is recorded as such, since its first question is
+// unknown. It returns the name used.
+func recordAdvisorQuestion(path, account, sessionID, name, prompt string, followUp bool) (string, error) {
+	if sessionID == "" {
+		return "", fmt.Errorf("no session ID")
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	err := updateAdvisorConversations(path, func(cf *advisorConversationFile) error {
+		c := cf.bySession(account, sessionID)
+		if c == nil {
+			if name == "" {
+				name = cf.uniqueName(prompt)
+			} else if other := cf.find(name); other != nil {
+				return fmt.Errorf("conversation '%s' already belongs to session %s", name, other.SessionID)
+			}
+			first := prompt
+			if followUp {
+				first = "(follow-up) " + prompt
+			}
+			cf.Conversations = append(cf.Conversations, advisorConversation{
+				Name: name, SessionID: sessionID, Account: account, Created: now, FirstPrompt: first,
+			})
+			c = &cf.Conversations[len(cf.Conversations)-1]
+		} else if name != "" && !strings.EqualFold(c.Name, name) {
+			if cf.find(name) != nil {
+				return fmt.Errorf("conversation '%s' already exists", name)
+			}
+			c.Name = name
+		}
+		c.LastUsed = now
+		c.Questions++
+		name = c.Name
+		return nil
+	})
+	return name, err
+}
+
+func registerAdvisorConversationTools(s *server.MCPServer) {
+	list := mcp.NewTool("kentik_list_ai_advisor_conversations",
+		mcp.WithDescription("List the AI Advisor conversations remembered locally: name, session ID, creation and last use, number of questions and the first question. Pass a name as conversation to kentik_ai_advisor to continue it."),
+		localTool(true, false),
+	)
+	s.AddTool(list, makeListAdvisorConversationsHandler())
+
+	rename := mcp.NewTool("kentik_rename_ai_advisor_conversation",
+		mcp.WithDescription("Rename a locally remembered AI Advisor conversation."),
+		localTool(false, false),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Current conversation name"),
+		),
+		mcp.WithString("new_name",
+			mcp.Required(),
+			mcp.Description("New conversation name"),
+		),
+	)
+	s.AddTool(rename, makeRenameAdvisorConversationHandler())
+
+	forget := mcp.NewTool("kentik_forget_ai_advisor_conversation",
+		mcp.WithDescription("Remove an AI Advisor conversation from the local store. The session itself stays in Kentik."),
+		localTool(false, true),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Conversation name"),
+		),
+	)
+	s.AddTool(forget, makeForgetAdvisorConversationHandler())
+}
+
+func makeListAdvisorConversationsHandler() server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path := optionsFrom(ctx).advisorStore()
+		advisorStoreMu.Lock()
+		cf, err := loadAdvisorConversations(path)
+		advisorStoreMu.Unlock()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load conversations: %v", err)), nil
+		}
+		if len(cf.Conversations) == 0 {
+			return mcp.NewToolResultText("No AI Advisor conversations yet. kentik_ai_advisor remembers every new session here."), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## AI Advisor Conversations (%d)\n\n", len(cf.Conversations)))
+		sb.WriteString(fmt.Sprintf("| %-30s | %-36s | %-10s | %-20s | %-20s | %4s | %-40s |\n",
+			"Name", "Session", "Account", "Created", "Last Used", "Qs", "First Question"))
+		sb.WriteString("|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 38) + "|" + strings.Repeat("-", 12) +
+			"|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 6) + "|" + strings.Repeat("-", 42) + "|\n")
+		for i := len(cf.Conversations) - 1; i >= 0; i-- {
+			c := cf.Conversations[i]
+			sb.WriteString(fmt.Sprintf("| %-30s | %-36s | %-10s | %-20s | %-20s | %4d | %-40s |\n",
+				truncateLabel(c.Name, 30), c.SessionID, truncateLabel(c.Account, 10), c.Created, c.LastUsed, c.Questions, firstLine(c.FirstPrompt, 40)))
+		}
+		sb.WriteString(fmt.Sprintf("\nStored in %s.\n", path))
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+func makeRenameAdvisorConversationHandler() server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, err := request.RequireString("name")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		newName, err := request.RequireString("new_name")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		newName = strings.TrimSpace(newName)
+		if newName == "" {
+			return mcp.NewToolResultError("new_name must not be empty"), nil
+		}
+		err = updateAdvisorConversations(optionsFrom(ctx).advisorStore(), func(cf *advisorConversationFile) error {
+			c := cf.find(name)
+			if c == nil {
+				return fmt.Errorf("conversation '%s' not found", name)
+			}
+			if other := cf.find(newName); other != nil && other != c {
+				return fmt.Errorf("conversation '%s' already exists", newName)
+			}
+			c.Name = newName
+			return nil
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to rename: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Conversation '%s' renamed to '%s'.", name, newName)), nil
+	}
+}
+
+func makeForgetAdvisorConversationHandler() server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, err := request.RequireString("name")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		var sessionID string
+		err = updateAdvisorConversations(optionsFrom(ctx).advisorStore(), func(cf *advisorConversationFile) error {
+			kept := cf.Conversations[:0]
+			for _, c := range cf.Conversations {
+				if strings.EqualFold(c.Name, name) {
+					sessionID = c.SessionID
+					continue
+				}
+				kept = append(kept, c)
+			}
+			if sessionID == "" {
+				return fmt.Errorf("conversation '%s' not found", name)
+			}
+			cf.Conversations = kept
+			return nil
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to forget: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Conversation '%s' forgotten (session %s remains in Kentik).", name, sessionID)), nil
+	}
+}
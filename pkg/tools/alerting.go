@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/awlx/kentik-mcp/pkg/kentik"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -15,6 +19,7 @@ func registerAlertingTools(s *server.MCPServer, client *kentik.Client) {
 	// List active alerts
 	listAlerts := mcp.NewTool("kentik_list_alerts",
 		mcp.WithDescription("List active alerts and alarms from Kentik. Shows current anomalies, threshold violations, and DDoS detections across your network."),
+		readOnlyTool(),
 		mcp.WithString("status",
 			mcp.Description("Filter by alert status: 'alarm' (active), 'ackReq' (needs acknowledgement), or leave empty for all."),
 		),
@@ -23,6 +28,69 @@ func registerAlertingTools(s *server.MCPServer, client *kentik.Client) {
 		),
 	)
 	s.AddTool(listAlerts, makeListAlertsHandler(client))
+
+	listPolicies := mcp.NewTool("kentik_list_alert_policies",
+		mcp.WithDescription("List alert policies with their dimensions, metrics and per-severity threshold conditions."),
+		readOnlyTool(),
+		mcp.WithString("name_filter",
+			mcp.Description("Only policies whose name contains this text"),
+		),
+		mcp.WithBoolean("enabled_only",
+			mcp.Description("Hide disabled policies. Default: false"),
+		),
+	)
+	s.AddTool(listPolicies, makeListAlertPoliciesHandler(client))
+
+	alarmHistory := mcp.NewTool("kentik_alarm_history",
+		mcp.WithDescription("Get alarm history for a time range, including cleared alarms, with their durations. Filter by policy, severity and dimension value."),
+		readOnlyTool(),
+		mcp.WithString("start_time",
+			mcp.Description("Start time in RFC3339 format. Default: end_time minus lookback_hours"),
+		),
+		mcp.WithString("end_time",
+			mcp.Description("End time in RFC3339 format. Default: now"),
+		),
+		mcp.WithNumber("lookback_hours",
+			mcp.Description("Window length when start_time is not set. Default: 24"),
+		),
+		mcp.WithString("policy",
+			mcp.Description("Policy ID, or text contained in the policy name"),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Severity, e.g. 'critical', 'major', 'minor'"),
+		),
+		mcp.WithString("dimension_value",
+			mcp.Description("Text contained in the alarm key (e.g. an IP, interface or ASN)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum alarms to list, newest first. Default: 100"),
+		),
+	)
+	s.AddTool(alarmHistory, makeAlarmHistoryHandler(client))
+
+	policyStats := mcp.NewTool("kentik_alert_policy_stats",
+		mcp.WithDescription("Per-policy alarm statistics over a time range: firing count and rate, distinct keys, median/max duration and total active time. Use it to find noisy or flapping policies."),
+		readOnlyTool(),
+		mcp.WithString("start_time",
+			mcp.Description("Start time in RFC3339 format. Default: end_time minus lookback_hours"),
+		),
+		mcp.WithString("end_time",
+			mcp.Description("End time in RFC3339 format. Default: now"),
+		),
+		mcp.WithNumber("lookback_hours",
+			mcp.Description("Window length when start_time is not set. Default: 24 (use 168 to review a week)"),
+		),
+		mcp.WithString("policy",
+			mcp.Description("Policy ID, or text contained in the policy name"),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Severity, e.g. 'critical', 'major', 'minor'"),
+		),
+		mcp.WithString("dimension_value",
+			mcp.Description("Text contained in the alarm key"),
+		),
+	)
+	s.AddTool(policyStats, makeAlertPolicyStatsHandler(client))
 }
 
 func makeListAlertsHandler(client *kentik.Client) server.ToolHandlerFunc {
@@ -34,28 +102,15 @@ func makeListAlertsHandler(client *kentik.Client) server.ToolHandlerFunc {
 
 		// Use V5 alerting API to get active alarms
 		path := fmt.Sprintf("/alerts-active/alarms?lookback_minutes=%d", int(lookbackMin))
-		data, err := client.V5("GET", path, nil)
+		data, err := client.V5(ctx, "GET", path, nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list alerts: %v", err)), nil
 		}
 
 		// Parse and summarize
-		var alarms []map[string]interface{}
-		if err := json.Unmarshal(data, &alarms); err != nil {
-			// Try alternate structure
-			var resp map[string]interface{}
-			if err2 := json.Unmarshal(data, &resp); err2 != nil {
-				return mcp.NewToolResultText(formatJSON(data)), nil
-			}
-			if a, ok := resp["alarms"].([]interface{}); ok {
-				for _, item := range a {
-					if m, ok := item.(map[string]interface{}); ok {
-						alarms = append(alarms, m)
-					}
-				}
-			} else {
-				return mcp.NewToolResultText(formatJSON(data)), nil
-			}
+		alarms, ok := parseAlarms(data)
+		if !ok {
+			return mcp.NewToolResultText(formatJSON(data)), nil
 		}
 
 		// Filter by status if specified
@@ -78,19 +133,20 @@ func makeListAlertsHandler(client *kentik.Client) server.ToolHandlerFunc {
 
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("## Active Alerts (%d)\n\n", len(alarms)))
-		sb.WriteString(fmt.Sprintf("| %-30s | %-15s | %-20s | %-30s |\n",
-			"Policy", "State", "Severity", "Dimension"))
-		sb.WriteString("|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 17) +
-			"|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 32) + "|\n")
+		sb.WriteString(fmt.Sprintf("| %-12s | %-30s | %-15s | %-20s | %-30s | %-20s |\n",
+			"Alarm ID", "Policy", "State", "Severity", "Dimension", "Key"))
+		sb.WriteString("|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 17) +
+			"|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 22) + "|\n")
 
 		for _, a := range alarms {
 			policy := fmt.Sprintf("%v", a["alert_policy_name"])
 			if policy == "<nil>" {
-				policy = fmt.Sprintf("%v", a["alert_id"])
+				policy = alarmString(a, "alert_id")
 			}
 			state := fmt.Sprintf("%v", a["alarm_state"])
 			severity := fmt.Sprintf("%v", a["alert_severity"])
 			dim := fmt.Sprintf("%v", a["alert_dimension"])
+			key := truncateLabel(alarmString(a, "alert_key"), 20)
 
 			if len(policy) > 30 {
 				policy = policy[:27] + "..."
@@ -99,8 +155,8 @@ func makeListAlertsHandler(client *kentik.Client) server.ToolHandlerFunc {
 				dim = dim[:27] + "..."
 			}
 
-			sb.WriteString(fmt.Sprintf("| %-30s | %-15s | %-20s | %-30s |\n",
-				policy, state, severity, dim))
+			sb.WriteString(fmt.Sprintf("| %-12s | %-30s | %-15s | %-20s | %-30s | %-20s |\n",
+				alarmString(a, "alarm_id"), policy, state, severity, dim, key))
 		}
 
 		sb.WriteString("\n<details><summary>Raw JSON</summary>\n\n```json\n")
@@ -110,3 +166,409 @@ func makeListAlertsHandler(client *kentik.Client) server.ToolHandlerFunc {
 		return mcp.NewToolResultText(sb.String()), nil
 	}
 }
+
+// parseAlarms decodes an alarms response, which is either a bare array or
+// an object with an "alarms" array. ok is false for any other shape.
+func parseAlarms(data []byte) (alarms []map[string]interface{}, ok bool) {
+	if err := json.Unmarshal(data, &alarms); err == nil {
+		return alarms, true
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	a, isList := resp["alarms"].([]interface{})
+	if !isList {
+		return nil, false
+	}
+	for _, item := range a {
+		if m, isMap := item.(map[string]interface{}); isMap {
+			alarms = append(alarms, m)
+		}
+	}
+	return alarms, true
+}
+
+// alarmString returns a field of an alarm as a string, or "" when missing.
+// Numbers are formatted without exponent so IDs such as 123456789 survive
+// JSON's float64 decoding.
+func alarmString(a map[string]interface{}, key string) string {
+	switch v := a[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// parseAlarmTime accepts the timestamp formats seen in alarm payloads.
+func parseAlarmTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05.000Z", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstString returns the first non-empty field of m among keys.
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s := alarmString(m, k); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// listField renders a field that may be a string or a list as a comma-separated string.
+func listField(m map[string]interface{}, key string) string {
+	switch v := m[key].(type) {
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(parts, ",")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// parsePolicies decodes a policies response, which is either a bare array or
+// an object wrapping the array in "policies" or "data".
+func parsePolicies(data []byte) ([]map[string]interface{}, bool) {
+	var policies []map[string]interface{}
+	if err := json.Unmarshal(data, &policies); err == nil {
+		return policies, true
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	for _, key := range []string{"policies", "data"} {
+		if list, isList := resp[key].([]interface{}); isList {
+			for _, item := range list {
+				if m, isMap := item.(map[string]interface{}); isMap {
+					policies = append(policies, m)
+				}
+			}
+			return policies, true
+		}
+	}
+	return nil, false
+}
+
+// describeThresholds summarizes a policy's thresholds as
+// "critical: bits_per_sec > 1000000; minor: ...".
+func describeThresholds(p map[string]interface{}) string {
+	thresholds, _ := p["thresholds"].([]interface{})
+	var parts []string
+	for _, t := range thresholds {
+		tm, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var conds []string
+		list, _ := tm["conditions"].([]interface{})
+		for _, c := range list {
+			cm, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			cond := strings.TrimSpace(fmt.Sprintf("%s %s %s",
+				firstString(cm, "metric", "type"), firstString(cm, "operator", "comparator"), firstString(cm, "value", "threshold")))
+			if cond != "" {
+				conds = append(conds, cond)
+			}
+		}
+		sev := firstString(tm, "severity")
+		if sev == "" {
+			sev = "threshold"
+		}
+		if len(conds) == 0 {
+			conds = append(conds, "(no conditions)")
+		}
+		parts = append(parts, sev+": "+strings.Join(conds, " and "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func makeListAlertPoliciesHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		data, err := client.V5(ctx, "GET", "/alerts/policies", nil)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list alert policies: %v", err)), nil
+		}
+		policies, ok := parsePolicies(data)
+		if !ok {
+			return mcp.NewToolResultText(formatJSON(data)), nil
+		}
+
+		nameFilter, _ := request.RequireString("name_filter")
+		enabledOnly := request.GetBool("enabled_only", false)
+
+		var sb strings.Builder
+		count := 0
+		var body strings.Builder
+		for _, p := range policies {
+			name := firstString(p, "policy_name", "name")
+			if nameFilter != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(nameFilter)) {
+				continue
+			}
+			enabled := firstString(p, "is_enabled", "enabled", "status")
+			if enabledOnly && enabled != "true" && !strings.EqualFold(enabled, "active") {
+				continue
+			}
+			count++
+			body.WriteString(fmt.Sprintf("### %s (ID %s)\n\n", name, firstString(p, "id", "policy_id")))
+			body.WriteString(fmt.Sprintf("- Enabled: %s\n", enabled))
+			if desc := firstString(p, "policy_description", "description"); desc != "" {
+				body.WriteString(fmt.Sprintf("- Description: %s\n", desc))
+			}
+			body.WriteString(fmt.Sprintf("- Dimensions: %s\n", listField(p, "dimensions")))
+			body.WriteString(fmt.Sprintf("- Metrics: %s\n", listField(p, "metric")))
+			if th := describeThresholds(p); th != "" {
+				body.WriteString(fmt.Sprintf("- Thresholds: %s\n", th))
+			}
+			body.WriteString("\n")
+		}
+		if count == 0 {
+			return mcp.NewToolResultText("No alert policies found."), nil
+		}
+		sb.WriteString(fmt.Sprintf("## Alert Policies (%d)\n\n", count))
+		sb.WriteString(body.String())
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// historyWindow reads start_time/end_time (RFC3339) or lookback_hours from the request.
+func historyWindow(request mcp.CallToolRequest) (time.Time, time.Time, error) {
+	end := time.Now().UTC()
+	if v, err := request.RequireString("end_time"); err == nil && v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_time %q: %v", v, err)
+		}
+		end = t.UTC()
+	}
+	lookback := 24.0
+	if lb, err := request.RequireFloat("lookback_hours"); err == nil && lb > 0 {
+		lookback = lb
+	}
+	start := end.Add(-time.Duration(lookback * float64(time.Hour)))
+	if v, err := request.RequireString("start_time"); err == nil && v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_time %q: %v", v, err)
+		}
+		start = t.UTC()
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_time must be before end_time")
+	}
+	return start, end, nil
+}
+
+// fetchAlarmHistory returns the alarm events in [start, end), filtered by
+// policy (name substring or ID), severity and dimension value.
+func fetchAlarmHistory(ctx context.Context, client *kentik.Client, request mcp.CallToolRequest, start, end time.Time) ([]map[string]interface{}, error) {
+	params := url.Values{}
+	params.Set("startTime", start.Format("2006-01-02T15:04:05"))
+	params.Set("endTime", end.Format("2006-01-02T15:04:05"))
+	params.Set("showAlarms", "1")
+	params.Set("showMitigations", "0")
+	data, err := client.V5(ctx, "GET", "/alerts-active/alerts-history?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	events, ok := parseAlarms(data)
+	if !ok {
+		return nil, fmt.Errorf("unexpected alarm history response")
+	}
+
+	policy, _ := request.RequireString("policy")
+	severity, _ := request.RequireString("severity")
+	dimValue, _ := request.RequireString("dimension_value")
+	var out []map[string]interface{}
+	for _, e := range events {
+		if policy != "" && alarmString(e, "alert_id") != policy &&
+			!strings.Contains(strings.ToLower(alarmString(e, "alert_policy_name")), strings.ToLower(policy)) {
+			continue
+		}
+		if severity != "" && !strings.EqualFold(alarmString(e, "alert_severity"), severity) {
+			continue
+		}
+		if dimValue != "" && !strings.Contains(strings.ToLower(alarmString(e, "alert_key")), strings.ToLower(dimValue)) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// alarmDuration returns how long an alarm was active, counting still-open
+// alarms up to end. ok is false when the start time is missing.
+func alarmDuration(a map[string]interface{}, end time.Time) (time.Duration, bool) {
+	start, ok := parseAlarmTime(alarmString(a, "alarm_start"))
+	if !ok {
+		return 0, false
+	}
+	stop := end
+	if t, ok := parseAlarmTime(alarmString(a, "alarm_end")); ok && !t.IsZero() && t.Year() > 1970 {
+		stop = t
+	}
+	if stop.Before(start) {
+		return 0, false
+	}
+	return stop.Sub(start), true
+}
+
+// formatDuration renders a duration compactly, e.g. "2h05m" or "45s".
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+func makeAlarmHistoryHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start, end, err := historyWindow(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		events, err := fetchAlarmHistory(ctx, client, request, start, end)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get alarm history: %v", err)), nil
+		}
+		limit := 100
+		if l, err := request.RequireFloat("limit"); err == nil && l > 0 {
+			limit = int(l)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## Alarm History %s → %s UTC (%d alarms)\n\n",
+			start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), len(events)))
+		if len(events) == 0 {
+			sb.WriteString("No alarms matched.\n")
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
+		sort.SliceStable(events, func(i, j int) bool {
+			return alarmString(events[i], "alarm_start") > alarmString(events[j], "alarm_start")
+		})
+
+		sb.WriteString(fmt.Sprintf("| %-12s | %-30s | %-10s | %-20s | %-25s | %-20s | %9s |\n",
+			"Alarm ID", "Policy", "Severity", "Started", "Key", "State", "Duration"))
+		sb.WriteString("|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 12) +
+			"|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 27) + "|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 11) + "|\n")
+		for i, e := range events {
+			if i >= limit {
+				sb.WriteString(fmt.Sprintf("\n*%d more alarms not shown; raise limit or narrow the filters.*\n", len(events)-limit))
+				break
+			}
+			dur := "—"
+			if d, ok := alarmDuration(e, end); ok {
+				dur = formatDuration(d)
+			}
+			sb.WriteString(fmt.Sprintf("| %-12s | %-30s | %-10s | %-20s | %-25s | %-20s | %9s |\n",
+				alarmString(e, "alarm_id"), truncateLabel(firstString(e, "alert_policy_name", "alert_id"), 30),
+				alarmString(e, "alert_severity"), truncateLabel(alarmString(e, "alarm_start"), 20),
+				truncateLabel(alarmString(e, "alert_key"), 25), truncateLabel(alarmString(e, "alarm_state"), 20), dur))
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// policyStats aggregates the alarms of one policy.
+type policyStats struct {
+	name      string
+	firings   int
+	keys      map[string]bool
+	durations []time.Duration
+	total     time.Duration
+}
+
+func makeAlertPolicyStatsHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start, end, err := historyWindow(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		events, err := fetchAlarmHistory(ctx, client, request, start, end)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get alarm history: %v", err)), nil
+		}
+		if len(events) == 0 {
+			return mcp.NewToolResultText("No alarms in the selected window."), nil
+		}
+
+		byPolicy := make(map[string]*policyStats)
+		for _, e := range events {
+			name := firstString(e, "alert_policy_name", "alert_id")
+			ps := byPolicy[name]
+			if ps == nil {
+				ps = &policyStats{name: name, keys: make(map[string]bool)}
+				byPolicy[name] = ps
+			}
+			ps.firings++
+			ps.keys[alarmString(e, "alert_key")] = true
+			if d, ok := alarmDuration(e, end); ok {
+				ps.durations = append(ps.durations, d)
+				ps.total += d
+			}
+		}
+		var list []*policyStats
+		for _, ps := range byPolicy {
+			list = append(list, ps)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].firings != list[j].firings {
+				return list[i].firings > list[j].firings
+			}
+			return list[i].name < list[j].name
+		})
+
+		hours := end.Sub(start).Hours()
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## Alert Policy Statistics %s → %s UTC\n\n",
+			start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04")))
+		sb.WriteString(fmt.Sprintf("%d alarms across %d policies.\n\n", len(events), len(list)))
+		sb.WriteString(fmt.Sprintf("| %-35s | %7s | %8s | %6s | %9s | %9s | %9s | %-14s |\n",
+			"Policy", "Firings", "Per Day", "Keys", "Median", "Max", "Active", "Assessment"))
+		sb.WriteString("|" + strings.Repeat("-", 37) + "|" + strings.Repeat("-", 9) + "|" + strings.Repeat("-", 10) +
+			"|" + strings.Repeat("-", 8) + "|" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", 11) +
+			"|" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", 16) + "|\n")
+		for _, ps := range list {
+			perDay := float64(ps.firings) / hours * 24
+			median, longest := "—", "—"
+			assessment := ""
+			if len(ps.durations) > 0 {
+				sort.Slice(ps.durations, func(i, j int) bool { return ps.durations[i] < ps.durations[j] })
+				med := ps.durations[len(ps.durations)/2]
+				median = formatDuration(med)
+				longest = formatDuration(ps.durations[len(ps.durations)-1])
+				// Frequent short alarms are the typical signature of a noisy threshold
+				if perDay >= 10 && med < 5*time.Minute {
+					assessment = "noisy (flapping)"
+				} else if perDay >= 10 {
+					assessment = "frequent"
+				}
+			}
+			sb.WriteString(fmt.Sprintf("| %-35s | %7d | %8.1f | %6d | %9s | %9s | %9s | %-14s |\n",
+				truncateLabel(ps.name, 35), ps.firings, perDay, len(ps.keys), median, longest, formatDuration(ps.total), assessment))
+		}
+		sb.WriteString("\n*Keys = distinct dimension values that fired. Active = summed alarm duration; open alarms count up to the window end.*\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
@@ -0,0 +1,29 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAlarmString(t *testing.T) {
+	var a map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"alarm_id": 123456789, "alert_value": 1.5, "alert_key": "10.0.0.1", "is_enabled": true, "alarm_end": null}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"alarm_id", "123456789"},
+		{"alert_value", "1.5"},
+		{"alert_key", "10.0.0.1"},
+		{"is_enabled", "true"},
+		{"alarm_end", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		if got := alarmString(a, tt.key); got != tt.want {
+			t.Errorf("alarmString(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
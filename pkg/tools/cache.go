@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/awlx/kentik-mcp/pkg/metrics"
+)
+
+// cacheKey identifies a cached GET response per client, so several Kentik
+// accounts never share entries.
+type cacheKey struct {
+	client *kentik.Client
+	path   string
+}
+
+type cacheEntry struct {
+	data    json.RawMessage
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[cacheKey]cacheEntry)
+)
+
+// cachedV5Get returns a V5 GET response, reusing a previous response for up
+// to ttl. A zero ttl disables caching. Errors are never cached.
+func cachedV5Get(ctx context.Context, client *kentik.Client, path string, ttl time.Duration) (json.RawMessage, error) {
+	if ttl <= 0 {
+		return client.V5(ctx, "GET", path, nil)
+	}
+	key := cacheKey{client, path}
+	cacheMu.Lock()
+	e, ok := cache[key]
+	cacheMu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		metrics.CacheLookups.Inc(path, "hit")
+		return e.data, nil
+	}
+	metrics.CacheLookups.Inc(path, "miss")
+
+	data, err := client.V5(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	cacheMu.Lock()
+	cache[key] = cacheEntry{data: data, expires: time.Now().Add(ttl)}
+	cacheMu.Unlock()
+	return data, nil
+}
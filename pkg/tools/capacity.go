@@ -11,23 +11,19 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerCapacityPlanTools(s *server.MCPServer, client *kentik.Client) {
+func registerCapacityPlanTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	capacityPlan := mcp.NewTool("kentik_capacity_plan",
 		mcp.WithDescription("Query interface capacity and utilization from Kentik. Shows current utilization as a percentage of interface speed, helping identify links approaching capacity. Groups by interface with speed, current usage, and utilization %."),
-		mcp.WithString("device_name",
-			mcp.Description("Comma-delimited device names."),
-		),
-		mcp.WithString("device_label",
-			mcp.Description("Auto-resolve devices by label."),
-		),
-		mcp.WithString("site_name",
-			mcp.Description("Auto-resolve devices by site."),
-		),
+		readOnlyTool(),
+		withDeviceSelector(),
 		mcp.WithString("interface_description_filter",
 			mcp.Description("Filter by interface description substring. E.g. 'pni', 'transit', 'uplink'."),
 		),
+		mcp.WithString("interface_class",
+			mcp.Description("Only interfaces of this configured class, e.g. 'transit', 'pni', 'ix', 'core' (see interface_classes in the config file)."),
+		),
 		mcp.WithNumber("lookback_seconds",
-			mcp.Description("Time range. Default: 3600"),
+			mcp.Description(fmt.Sprintf("Time range. Default: %d", int(opts.lookback()))),
 		),
 		mcp.WithNumber("utilization_threshold",
 			mcp.Description("Only show interfaces above this utilization %. Default: 0 (show all)"),
@@ -38,9 +34,13 @@ func registerCapacityPlanTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeCapacityPlanHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		resolvedDevices := resolveDeviceShortcuts(client, request)
+		opts := optionsFrom(ctx)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
 
-		lookback := 3600.0
+		lookback := opts.lookback()
 		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
 			lookback = lb
 		}
@@ -49,10 +49,14 @@ func makeCapacityPlanHandler(client *kentik.Client) server.ToolHandlerFunc {
 			threshold = th
 		}
 		ifDescFilter, _ := request.RequireString("interface_description_filter")
+		ifClass, _ := request.RequireString("interface_class")
+		if err := opts.classes.check(ifClass); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Query egress traffic by source interface
 		topx := 250
-		if ifDescFilter == "" {
+		if ifDescFilter == "" && ifClass == "" {
 			topx = 50
 		}
 
@@ -69,9 +73,8 @@ func makeCapacityPlanHandler(client *kentik.Client) server.ToolHandlerFunc {
 			"all_selected":     true,
 		}
 
-		if resolvedDevices != "" {
-			query["device_name"] = resolvedDevices
-			query["all_selected"] = false
+		if len(resolution.Devices) > 0 {
+			resolution.apply(query)
 		} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
 			query["device_name"] = dn
 			query["all_selected"] = false
@@ -83,7 +86,7 @@ func makeCapacityPlanHandler(client *kentik.Client) server.ToolHandlerFunc {
 			},
 		}
 
-		data, err := client.V5("POST", "/query/topXdata", body)
+		data, err := client.V5(ctx, "POST", "/query/topXdata", body)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Query failed: %v", err)), nil
 		}
@@ -111,14 +114,18 @@ func makeCapacityPlanHandler(client *kentik.Client) server.ToolHandlerFunc {
 			}
 			entries = filtered
 		}
+		if ifClass != "" {
+			entries = opts.classes.filter(entries, ifClass)
+		}
 
 		// We need interface speeds — fetch from the API for each device
 		// For now, estimate based on common speeds or show raw bandwidth
 		var sb strings.Builder
+		sb.WriteString(resolution.note())
 		sb.WriteString(fmt.Sprintf("## Interface Capacity Report (%d interfaces)\n\n", len(entries)))
-		sb.WriteString(fmt.Sprintf("| %-65s | %14s | %14s | %14s |\n",
-			"Interface", "Avg Egress", "P95 Egress", "Max Egress"))
-		sb.WriteString("|" + strings.Repeat("-", 67) + "|" + strings.Repeat("-", 16) +
+		sb.WriteString(fmt.Sprintf("| %-65s | %-16s | %14s | %14s | %14s |\n",
+			"Interface", "Class", "Avg Egress", "P95 Egress", "Max Egress"))
+		sb.WriteString("|" + strings.Repeat("-", 67) + "|" + strings.Repeat("-", 18) + "|" + strings.Repeat("-", 16) +
 			"|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 16) + "|\n")
 
 		shown := 0
@@ -137,12 +144,13 @@ func makeCapacityPlanHandler(client *kentik.Client) server.ToolHandlerFunc {
 			}
 
 			key := fmt.Sprintf("%v", e["key"])
+			class := truncateLabel(opts.classes.label(key), 16)
 			if len(key) > 65 {
 				key = key[:62] + "..."
 			}
 
-			sb.WriteString(fmt.Sprintf("| %-65s | %14s | %14s | %14s |\n",
-				key, formatBitsPerSec(avg), formatBitsPerSec(p95), formatBitsPerSec(max)))
+			sb.WriteString(fmt.Sprintf("| %-65s | %-16s | %14s | %14s | %14s |\n",
+				key, class, formatBitsPerSec(avg), formatBitsPerSec(p95), formatBitsPerSec(max)))
 			shown++
 		}
 
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compiledClass is an InterfaceClass with its patterns compiled.
+type compiledClass struct {
+	name        string
+	description *regexp.Regexp
+	provider    *regexp.Regexp
+}
+
+// classSet is the configured classes, in priority order.
+type classSet []compiledClass
+
+func compileInterfaceClasses(classes []InterfaceClass) (classSet, error) {
+	out := make(classSet, 0, len(classes))
+	for _, c := range classes {
+		desc, err := regexp.Compile(c.Description)
+		if err != nil {
+			return nil, fmt.Errorf("interface class %q: invalid description pattern: %v", c.Name, err)
+		}
+		cc := compiledClass{name: c.Name, description: desc}
+		if c.Provider != "" {
+			if cc.provider, err = regexp.Compile(c.Provider); err != nil {
+				return nil, fmt.Errorf("interface class %q: invalid provider pattern: %v", c.Name, err)
+			}
+		}
+		out = append(out, cc)
+	}
+	return out, nil
+}
+
+// classify returns the first class whose pattern matches the
+// interface key or description, and the provider extracted from it.
+func (cs classSet) classify(desc string) (class, provider string) {
+	for _, c := range cs {
+		if !c.description.MatchString(desc) {
+			continue
+		}
+		if c.provider != nil {
+			if m := c.provider.FindStringSubmatch(desc); len(m) > 1 {
+				provider = m[1]
+			}
+		}
+		return c.name, provider
+	}
+	return "", ""
+}
+
+// label renders a class and provider as "transit/Lumen".
+func (cs classSet) label(desc string) string {
+	class, provider := cs.classify(desc)
+	if provider != "" {
+		return class + "/" + provider
+	}
+	return class
+}
+
+// check validates an interface_class parameter against the
+// configured classes.
+func (cs classSet) check(name string) error {
+	if name == "" {
+		return nil
+	}
+	var names []string
+	for _, c := range cs {
+		if strings.EqualFold(c.name, name) {
+			return nil
+		}
+		names = append(names, c.name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no interface classes are configured")
+	}
+	return fmt.Errorf("unknown interface class %q; configured: %s", name, strings.Join(names, ", "))
+}
+
+// filter keeps the topX entries whose key falls in the given class.
+func (cs classSet) filter(entries []map[string]interface{}, class string) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, e := range entries {
+		if c, _ := cs.classify(fmt.Sprintf("%v", e["key"])); strings.EqualFold(c, class) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -35,6 +35,7 @@ type QueryContextFile struct {
 func registerContextTools(s *server.MCPServer) {
 	saveContext := mcp.NewTool("kentik_save_context",
 		mcp.WithDescription("Save a named query context (device group + filters) for reuse. Contexts are stored in ~/.kentik-mcp-contexts.json. Use context_name on query/compare tools to apply saved parameters."),
+		localTool(false, true),
 		mcp.WithString("name",
 			mcp.Required(),
 			mcp.Description("Unique name for this context. E.g. 'borders', 'external-traffic', 'core-routers'."),
@@ -68,11 +69,13 @@ func registerContextTools(s *server.MCPServer) {
 
 	listContexts := mcp.NewTool("kentik_list_contexts",
 		mcp.WithDescription("List all saved query contexts. Shows the name, description, and parameters of each saved context."),
+		localTool(true, false),
 	)
 	s.AddTool(listContexts, makeListContextsHandler())
 
 	deleteContext := mcp.NewTool("kentik_delete_context",
 		mcp.WithDescription("Delete a saved query context by name."),
+		localTool(false, true),
 		mcp.WithString("name",
 			mcp.Required(),
 			mcp.Description("Name of the context to delete."),
@@ -81,13 +84,17 @@ func registerContextTools(s *server.MCPServer) {
 	s.AddTool(deleteContext, makeDeleteContextHandler())
 }
 
-func contextFilePath() string {
+// contextFile returns the saved contexts location.
+func (o Options) contextFile() string {
+	if o.ContextFile != "" {
+		return o.ContextFile
+	}
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".kentik-mcp-contexts.json")
 }
 
-func loadContexts() (*QueryContextFile, error) {
-	data, err := os.ReadFile(contextFilePath())
+func loadContexts(path string) (*QueryContextFile, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &QueryContextFile{}, nil
@@ -101,17 +108,18 @@ func loadContexts() (*QueryContextFile, error) {
 	return &cf, nil
 }
 
-func saveContexts(cf *QueryContextFile) error {
+func saveContexts(path string, cf *QueryContextFile) error {
 	data, err := json.MarshalIndent(cf, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(contextFilePath(), data, 0644)
+	return os.WriteFile(path, data, 0644)
 }
 
-// GetContext returns a saved context by name, or nil if not found.
-func GetContext(name string) *QueryContext {
-	cf, err := loadContexts()
+// GetContext returns a saved context from the file at path by name, or nil
+// if not found.
+func GetContext(path, name string) *QueryContext {
+	cf, err := loadContexts(path)
 	if err != nil {
 		return nil
 	}
@@ -141,7 +149,8 @@ func makeSaveContextHandler() server.ToolHandlerFunc {
 		qc.Port, _ = request.RequireString("port")
 		qc.DstAS, _ = request.RequireString("dst_as")
 
-		cf, err := loadContexts()
+		path := optionsFrom(ctx).contextFile()
+		cf, err := loadContexts(path)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to load contexts: %v", err)), nil
 		}
@@ -159,17 +168,18 @@ func makeSaveContextHandler() server.ToolHandlerFunc {
 			cf.Contexts = append(cf.Contexts, qc)
 		}
 
-		if err := saveContexts(cf); err != nil {
+		if err := saveContexts(path, cf); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to save: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("Context '%s' saved (%s).", name, contextFilePath())), nil
+		return mcp.NewToolResultText(fmt.Sprintf("Context '%s' saved (%s).", name, path)), nil
 	}
 }
 
 func makeListContextsHandler() server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		cf, err := loadContexts()
+		path := optionsFrom(ctx).contextFile()
+		cf, err := loadContexts(path)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to load contexts: %v", err)), nil
 		}
@@ -219,7 +229,8 @@ func makeDeleteContextHandler() server.ToolHandlerFunc {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		cf, err := loadContexts()
+		path := optionsFrom(ctx).contextFile()
+		cf, err := loadContexts(path)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to load contexts: %v", err)), nil
 		}
@@ -239,7 +250,7 @@ func makeDeleteContextHandler() server.ToolHandlerFunc {
 		}
 		cf.Contexts = newContexts
 
-		if err := saveContexts(cf); err != nil {
+		if err := saveContexts(path, cf); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to save: %v", err)), nil
 		}
 
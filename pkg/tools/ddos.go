@@ -0,0 +1,393 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// reflectionPorts maps well-known UDP source ports to the amplification
+// vector they indicate.
+var reflectionPorts = map[string]string{
+	"19":    "CharGEN",
+	"53":    "DNS",
+	"111":   "Portmap",
+	"123":   "NTP",
+	"137":   "NetBIOS",
+	"161":   "SNMP",
+	"389":   "CLDAP",
+	"1900":  "SSDP",
+	"3283":  "ARD",
+	"3702":  "WS-Discovery",
+	"10001": "Ubiquiti",
+	"11211": "memcached",
+}
+
+// protocolNames maps IP protocol numbers to names for filter suggestions.
+var protocolNames = map[string]string{"1": "icmp", "6": "tcp", "17": "udp", "47": "gre", "50": "esp"}
+
+func registerDDoSTools(s *server.MCPServer, client *kentik.Client) {
+	ddosTriage := mcp.NewTool("kentik_ddos_triage",
+		mcp.WithDescription("Triage a (suspected) DDoS attack. Given an alarm ID from kentik_list_alerts, or a target IP/prefix and time window, runs follow-up flow queries toward the target (top source ASNs, source countries, protocols and ports, packet sizes, ingress interfaces and connectivity types) and returns a concise attack profile with suggested mitigation filters."),
+		readOnlyTool(),
+		mcp.WithString("alarm_id",
+			mcp.Description("Alarm ID from kentik_list_alerts. The target and window are taken from the alarm."),
+		),
+		mcp.WithString("target",
+			mcp.Description("Target IP or CIDR prefix under attack. Required when alarm_id is not given; overrides the alarm's key when both are set."),
+		),
+		mcp.WithNumber("lookback_seconds",
+			mcp.Description("Window to analyse. Default: from the alarm start (min 15 minutes), or 900 without an alarm."),
+		),
+		mcp.WithNumber("alarm_lookback_minutes",
+			mcp.Description("How far back to search for alarm_id. Default: 1440"),
+		),
+		mcp.WithNumber("topx",
+			mcp.Description("Rows per breakdown. Default: 8"),
+		),
+		withDeviceSelector(),
+	)
+	s.AddTool(ddosTriage, makeDDoSTriageHandler(client))
+}
+
+// findAlarm looks up an active alarm by ID.
+func findAlarm(ctx context.Context, client *kentik.Client, alarmID string, lookbackMin int) (map[string]interface{}, error) {
+	data, err := client.V5(ctx, "GET", fmt.Sprintf("/alerts-active/alarms?lookback_minutes=%d", lookbackMin), nil)
+	if err != nil {
+		return nil, err
+	}
+	alarms, ok := parseAlarms(data)
+	if !ok {
+		return nil, fmt.Errorf("unexpected alarms response")
+	}
+	for _, a := range alarms {
+		if alarmString(a, "alarm_id") == alarmID {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("alarm %s not found in the last %d minutes", alarmID, lookbackMin)
+}
+
+// alarmTarget picks an IP or prefix out of an alarm's key, which may hold
+// several comma-separated dimension values.
+func alarmTarget(a map[string]interface{}) string {
+	for _, field := range []string{"alert_key", "alert_dimension_value", "alert_key_lookup"} {
+		for _, part := range strings.Split(alarmString(a, field), ",") {
+			part = strings.TrimSpace(part)
+			if net.ParseIP(part) != nil {
+				return part
+			}
+			if _, _, err := net.ParseCIDR(part); err == nil {
+				return part
+			}
+		}
+	}
+	return ""
+}
+
+func makeDDoSTriageHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
+
+		alarmID, _ := request.RequireString("alarm_id")
+		target, _ := request.RequireString("target")
+		target = strings.TrimSpace(target)
+		topx := 8.0
+		if tx, err := request.RequireFloat("topx"); err == nil && tx > 0 {
+			topx = tx
+		}
+
+		lookback := 900.0
+		var alarm map[string]interface{}
+		if alarmID != "" {
+			alarmLookback := 1440.0
+			if v, err := request.RequireFloat("alarm_lookback_minutes"); err == nil && v > 0 {
+				alarmLookback = v
+			}
+			a, err := findAlarm(ctx, client, alarmID, int(alarmLookback))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to load alarm: %v", err)), nil
+			}
+			alarm = a
+			if target == "" {
+				target = alarmTarget(a)
+			}
+			if start, ok := parseAlarmTime(alarmString(a, "alarm_start")); ok {
+				lookback = max(time.Since(start).Seconds()+300, 900)
+			}
+		}
+		if lb, err := request.RequireFloat("lookback_seconds"); err == nil && lb > 0 {
+			lookback = lb
+		}
+		if target == "" {
+			return mcp.NewToolResultError("No target: pass target (IP or CIDR), or an alarm_id whose key is an IP address."), nil
+		}
+
+		// All breakdowns are restricted to traffic toward the target
+		targetReq := withArguments(request, map[string]string{"dst_ip": target})
+		filtersObj := buildFilters(targetReq)
+
+		mkQuery := func(metric string, dims []string, n int) map[string]interface{} {
+			outsort := "avg_bits_per_sec"
+			if metric == "packets" {
+				outsort = "avg_pkts_per_sec"
+			}
+			q := map[string]interface{}{
+				"metric":           metric,
+				"dimension":        dims,
+				"topx":             n,
+				"depth":            max(n*2, 25),
+				"fastData":         "Auto",
+				"outsort":          outsort,
+				"lookback_seconds": int(lookback),
+				"time_format":      "UTC",
+				"hostname_lookup":  true,
+				"all_selected":     true,
+				"filters_obj":      filtersObj,
+			}
+			if len(resolution.Devices) > 0 {
+				resolution.apply(q)
+			} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
+				q["device_name"] = dn
+				q["all_selected"] = false
+			}
+			return q
+		}
+
+		n := int(topx)
+		queries := []subQuery{
+			{"total bps", mkQuery("bytes", []string{"Traffic"}, 1)},
+			{"total pps", mkQuery("packets", []string{"Traffic"}, 1)},
+			{"Top Source ASNs", mkQuery("packets", []string{"AS_src"}, n)},
+			{"Source Countries", mkQuery("packets", []string{"Geography_src"}, n)},
+			{"Protocols", mkQuery("packets", []string{"Proto"}, n)},
+			{"Source Ports", mkQuery("packets", []string{"Port_src"}, n)},
+			{"Destination Ports", mkQuery("packets", []string{"Port_dst"}, n)},
+			{"Packet Sizes", mkQuery("packets", []string{"sampledpktsize"}, n)},
+			{"Ingress Interfaces", mkQuery("bytes", []string{"InterfaceID_src"}, n)},
+			{"Ingress Connectivity", mkQuery("bytes", []string{"i_src_connect_type_name"}, n)},
+		}
+		report := runQueries(ctx, client, queries)
+		if report.failed() == len(report.Results) {
+			return mcp.NewToolResultError(fmt.Sprintf("All triage queries failed, e.g.: %v", report.Results[0].Err)), nil
+		}
+
+		sum := func(idx int, valKey string) float64 {
+			total := 0.0
+			for _, e := range topXEntries(report.Results[idx].Data) {
+				v, _ := e[valKey].(float64)
+				total += v
+			}
+			return total
+		}
+		totalBps := sum(0, "avg_bits_per_sec")
+		totalPps := sum(1, "avg_pkts_per_sec")
+		maxBps := sum(0, "max_bits_per_sec")
+		maxPps := sum(1, "max_pkts_per_sec")
+
+		var sb strings.Builder
+		sb.WriteString(resolution.note())
+		sb.WriteString(fmt.Sprintf("## DDoS Triage: %s (last %s)\n\n", target, formatLookback(lookback)))
+		if alarm != nil {
+			sb.WriteString(fmt.Sprintf("Alarm %s — policy %s, severity %s, started %s, metric %s = %s\n\n",
+				alarmID, alarmString(alarm, "alert_policy_name"), alarmString(alarm, "alert_severity"),
+				alarmString(alarm, "alarm_start"), alarmString(alarm, "alert_metric"), alarmString(alarm, "alert_value")))
+		}
+		sb.WriteString(report.status())
+
+		// Profile
+		profile := ddosProfile{target: target}
+		profile.topProto, profile.topProtoShare = topShare(topXEntries(report.Results[4].Data), "avg_pkts_per_sec")
+		profile.topSrcPort, profile.topSrcPortShare = topShare(topXEntries(report.Results[5].Data), "avg_pkts_per_sec")
+		profile.topDstPort, profile.topDstPortShare = topShare(topXEntries(report.Results[6].Data), "avg_pkts_per_sec")
+		_, profile.topASNShare = topShare(topXEntries(report.Results[2].Data), "avg_pkts_per_sec")
+		profile.asnCount = len(topXEntries(report.Results[2].Data))
+		if totalPps > 0 {
+			profile.avgPktSize = totalBps / 8 / totalPps
+		}
+
+		sb.WriteString("### Attack Profile\n\n")
+		sb.WriteString(fmt.Sprintf("- Volume: %s avg / %s max, %s pps avg / %s pps max\n",
+			formatBitsPerSec(totalBps), formatBitsPerSec(maxBps), formatRate(totalPps, "packets"), formatRate(maxPps, "packets")))
+		if profile.avgPktSize > 0 {
+			sb.WriteString(fmt.Sprintf("- Average packet size: %.0f bytes (%s)\n", profile.avgPktSize, profile.sizeClass()))
+		}
+		for _, line := range profile.findings() {
+			sb.WriteString("- " + line + "\n")
+		}
+
+		sb.WriteString("\n### Suggested Mitigation Filters\n\n")
+		for _, f := range profile.mitigations() {
+			sb.WriteString("- " + f + "\n")
+		}
+		sb.WriteString("\n*Suggestions are derived from sampled flow data; validate against legitimate traffic to the target before applying.*\n")
+
+		// Breakdowns
+		for i := 2; i < len(report.Results); i++ {
+			r := report.Results[i]
+			sb.WriteString(fmt.Sprintf("\n### %s\n\n", r.Label))
+			if r.Err != nil {
+				sb.WriteString(fmt.Sprintf("Query failed: %v\n", r.Err))
+				continue
+			}
+			metric := queries[i].Query["metric"].(string)
+			valKey := "avg_bits_per_sec"
+			total := totalBps
+			if metric == "packets" {
+				valKey = "avg_pkts_per_sec"
+				total = totalPps
+			}
+			entries := topXEntries(r.Data)
+			if len(entries) == 0 {
+				sb.WriteString("No data.\n")
+				continue
+			}
+			for _, e := range entries {
+				v, _ := e[valKey].(float64)
+				share := 0.0
+				if total > 0 {
+					share = v / total * 100
+				}
+				sb.WriteString(fmt.Sprintf("- %s: %s (%.1f%%)\n", fmt.Sprintf("%v", e["key"]), formatRate(v, metric), share))
+			}
+		}
+
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// topShare returns the key of the first row and its share of all rows.
+func topShare(entries []map[string]interface{}, valKey string) (string, float64) {
+	if len(entries) == 0 {
+		return "", 0
+	}
+	total := 0.0
+	for _, e := range entries {
+		v, _ := e[valKey].(float64)
+		total += v
+	}
+	first, _ := entries[0][valKey].(float64)
+	if total == 0 {
+		return fmt.Sprintf("%v", entries[0]["key"]), 0
+	}
+	return fmt.Sprintf("%v", entries[0]["key"]), first / total * 100
+}
+
+// ddosProfile holds the derived characteristics of an attack.
+type ddosProfile struct {
+	target          string
+	topProto        string
+	topProtoShare   float64
+	topSrcPort      string
+	topSrcPortShare float64
+	topDstPort      string
+	topDstPortShare float64
+	topASNShare     float64
+	asnCount        int
+	avgPktSize      float64
+}
+
+func (p ddosProfile) sizeClass() string {
+	switch {
+	case p.avgPktSize < 100:
+		return "small packets — typical of SYN/ACK floods or pps-oriented attacks"
+	case p.avgPktSize > 1000:
+		return "large packets — typical of amplification or fragment floods"
+	}
+	return "mixed sizes"
+}
+
+// protoNumber returns the protocol number from keys like "UDP (17)" or "17".
+func protoNumber(key string) string {
+	if n := asnFromKey(key); n != "" {
+		if _, err := strconv.Atoi(n); err == nil {
+			return n
+		}
+	}
+	lower := strings.ToLower(key)
+	for num, name := range protocolNames {
+		if strings.Contains(lower, name) {
+			return num
+		}
+	}
+	return ""
+}
+
+// portNumber returns the port from keys like "ntp (123)" or "123".
+func portNumber(key string) string {
+	n := asnFromKey(key)
+	if _, err := strconv.Atoi(n); err == nil {
+		return n
+	}
+	return ""
+}
+
+func (p ddosProfile) vector() string {
+	if protoNumber(p.topProto) == "17" && p.topSrcPortShare >= 50 {
+		if v, ok := reflectionPorts[portNumber(p.topSrcPort)]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func (p ddosProfile) findings() []string {
+	var out []string
+	if p.topProto != "" {
+		out = append(out, fmt.Sprintf("Dominant protocol: %s (%.0f%% of packets)", p.topProto, p.topProtoShare))
+	}
+	if v := p.vector(); v != "" {
+		out = append(out, fmt.Sprintf("Likely %s reflection/amplification: source port %s carries %.0f%% of packets", v, p.topSrcPort, p.topSrcPortShare))
+	} else if p.topSrcPort != "" && p.topSrcPortShare >= 50 {
+		out = append(out, fmt.Sprintf("Concentrated source port: %s (%.0f%%)", p.topSrcPort, p.topSrcPortShare))
+	}
+	if p.topDstPort != "" && p.topDstPortShare >= 50 {
+		out = append(out, fmt.Sprintf("Targeted destination port: %s (%.0f%%)", p.topDstPort, p.topDstPortShare))
+	}
+	switch {
+	case p.asnCount == 0:
+	case p.topASNShare >= 50:
+		out = append(out, fmt.Sprintf("Sources concentrated: top ASN sends %.0f%% of packets", p.topASNShare))
+	default:
+		out = append(out, fmt.Sprintf("Sources distributed: top ASN sends only %.0f%% of packets among the top %d", p.topASNShare, p.asnCount))
+	}
+	return out
+}
+
+func (p ddosProfile) mitigations() []string {
+	var out []string
+	proto := protoNumber(p.topProto)
+	protoName := protocolNames[proto]
+	srcPort := portNumber(p.topSrcPort)
+	dstPort := portNumber(p.topDstPort)
+
+	if v := p.vector(); v != "" {
+		out = append(out,
+			fmt.Sprintf("Flowspec: destination %s, protocol udp, source-port %s → discard (blocks %s reflection)", p.target, srcPort, v),
+			fmt.Sprintf("ACL: deny udp any eq %s host/prefix %s", srcPort, p.target))
+	} else if protoName != "" && p.topProtoShare >= 80 {
+		rule := fmt.Sprintf("Flowspec: destination %s, protocol %s", p.target, protoName)
+		if dstPort != "" && p.topDstPortShare >= 50 {
+			rule += ", destination-port " + dstPort
+		}
+		out = append(out, rule+" → rate-limit to the normal baseline")
+	}
+	if p.avgPktSize > 1200 && proto == "17" {
+		out = append(out, fmt.Sprintf("Flowspec: destination %s, fragment is-fragment → discard (large UDP packets suggest fragments)", p.target))
+	}
+	if p.topASNShare >= 50 {
+		out = append(out, "Contact the top source ASN's NOC / filter at the ingress interface carrying it")
+	}
+	out = append(out, fmt.Sprintf("If volume threatens upstream links: RTBH for %s, or divert to scrubbing", p.target))
+	return out
+}
@@ -12,6 +12,7 @@ import (
 func registerDimensionTools(s *server.MCPServer) {
 	listDimensions := mcp.NewTool("kentik_list_dimensions",
 		mcp.WithDescription("List all available Kentik query dimensions with descriptions. Use this to find the correct dimension name for kentik_query_data or kentik_query_compare."),
+		localTool(true, false),
 		mcp.WithString("search",
 			mcp.Description("Search term to filter dimensions (case-insensitive). E.g. 'ip', 'as', 'port', 'interface', 'geo', 'connect'."),
 		),
@@ -31,6 +32,7 @@ func makeListDimensionsHandler() server.ToolHandlerFunc {
 		{"Port_src", "Source L4 port"},
 		{"Port_dst", "Destination L4 port"},
 		{"Proto", "IP protocol number (6=TCP, 17=UDP, 1=ICMP)"},
+		{"sampledpktsize", "Sampled packet size in bytes"},
 		{"VLAN_src", "Source VLAN ID"},
 		{"VLAN_dst", "Destination VLAN ID"},
 		{"src_eth_mac", "Source MAC address"},
@@ -45,6 +47,10 @@ func makeListDimensionsHandler() server.ToolHandlerFunc {
 		{"src_nexthop_asn", "Source next-hop ASN"},
 		{"src_second_asn", "Second ASN in source AS path"},
 		{"src_third_asn", "Third ASN in source AS path"},
+		{"dst_bgp_aspath", "Destination BGP AS path"},
+		{"dst_nexthop_asn", "Destination next-hop ASN"},
+		{"dst_second_asn", "Second ASN in destination AS path"},
+		{"dst_third_asn", "Third ASN in destination AS path"},
 
 		// Geography
 		{"Geography_src", "Source country"},
@@ -57,6 +63,7 @@ func makeListDimensionsHandler() server.ToolHandlerFunc {
 		// Device / Interface
 		{"i_device_id", "Device ID"},
 		{"i_device_site_name", "Device site name"},
+		{"i_ult_exit_site", "Ultimate exit site (where traffic leaves the network)"},
 		{"InterfaceID_src", "Source interface (with description)"},
 		{"InterfaceID_dst", "Destination interface (with description)"},
 		{"i_src_connect_type_name", "Source connectivity type (backbone, free_pni, transit, ix)"},
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+)
+
+// subQuery is one topXdata query issued by a fan-out tool.
+type subQuery struct {
+	Label string
+	Query map[string]interface{}
+}
+
+// subQueryResult is the outcome of a subQuery.
+type subQueryResult struct {
+	Label   string
+	Data    json.RawMessage
+	Err     error
+	Elapsed time.Duration
+}
+
+// fanoutReport summarizes a batch of sub-queries for the tool output.
+type fanoutReport struct {
+	Results     []subQueryResult
+	Elapsed     time.Duration
+	Concurrency int
+}
+
+// topXBody wraps a single query in the /query/topXdata request envelope.
+func topXBody(query map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"queries": []map[string]interface{}{
+			{"query": query, "bucket": "Left +Y Axis", "bucketIndex": 0, "isOverlay": false},
+		},
+	}
+}
+
+// runQueries executes all queries concurrently. The client caps in-flight
+// /query/ requests (at most kentik.QueryConcurrency), so callers may pass any
+// number of queries. Results are returned in input order.
+func runQueries(ctx context.Context, client *kentik.Client, queries []subQuery) *fanoutReport {
+	start := time.Now()
+	results := make([]subQueryResult, len(queries))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(idx int, q subQuery) {
+			defer wg.Done()
+			t0 := time.Now()
+			data, err := client.V5(ctx, "POST", "/query/topXdata", topXBody(q.Query))
+			results[idx] = subQueryResult{Label: q.Label, Data: data, Err: err, Elapsed: time.Since(t0)}
+		}(i, q)
+	}
+	wg.Wait()
+	return &fanoutReport{Results: results, Elapsed: time.Since(start), Concurrency: client.QueryConcurrency()}
+}
+
+// failed returns the number of sub-queries that returned an error.
+func (r *fanoutReport) failed() int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// status renders a one-line timing summary plus a line per failed
+// sub-query, e.g. "> 2/3 sub-queries succeeded in 3.1s (max 4 concurrent)".
+func (r *fanoutReport) status() string {
+	var sb strings.Builder
+	ok := len(r.Results) - r.failed()
+	sb.WriteString(fmt.Sprintf("> %d/%d sub-queries succeeded in %s (max %d concurrent):",
+		ok, len(r.Results), r.Elapsed.Round(100*time.Millisecond), r.Concurrency))
+	for _, res := range r.Results {
+		mark := "✓"
+		if res.Err != nil {
+			mark = "✗"
+		}
+		sb.WriteString(fmt.Sprintf(" %s %s %s;", mark, res.Label, res.Elapsed.Round(100*time.Millisecond)))
+	}
+	sb.WriteString("\n")
+	for _, res := range r.Results {
+		if res.Err != nil {
+			sb.WriteString(fmt.Sprintf("> ✗ %s failed: %v\n", res.Label, res.Err))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
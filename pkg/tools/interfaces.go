@@ -15,6 +15,7 @@ import (
 func registerInterfaceTools(s *server.MCPServer, client *kentik.Client) {
 	listInterfaces := mcp.NewTool("kentik_list_interfaces",
 		mcp.WithDescription("List all interfaces on a specific Kentik device."),
+		readOnlyTool(),
 		mcp.WithString("device_id",
 			mcp.Required(),
 			mcp.Description("The ID of the device whose interfaces to list"),
@@ -23,12 +24,15 @@ func registerInterfaceTools(s *server.MCPServer, client *kentik.Client) {
 	s.AddTool(listInterfaces, makeListInterfacesHandler(client))
 
 	listAllInterfaces := mcp.NewTool("kentik_list_all_interfaces",
-		mcp.WithDescription("List all interfaces across all Kentik devices. Fetches devices first, then queries interfaces for each device concurrently (respecting rate limits). Returns a JSON array with device_id, device_name, and interfaces for each device."),
+		mcp.WithDescription("List all interfaces across active Kentik devices, optionally narrowed with the device selectors (site, label, name pattern, type). Fetches devices first, then queries interfaces for each device concurrently (respecting rate limits). Returns a JSON array with device_id, device_name, and interfaces for each device."),
+		readOnlyTool(),
+		withDeviceSelector(),
 	)
 	s.AddTool(listAllInterfaces, makeListAllInterfacesHandler(client))
 
 	getInterface := mcp.NewTool("kentik_get_interface",
 		mcp.WithDescription("Get detailed information about a specific interface on a device."),
+		readOnlyTool(),
 		mcp.WithString("device_id",
 			mcp.Required(),
 			mcp.Description("The ID of the device"),
@@ -47,7 +51,7 @@ func makeListInterfacesHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V5("GET", fmt.Sprintf("/device/%s/interfaces", deviceID), nil)
+		data, err := client.V5(ctx, "GET", fmt.Sprintf("/device/%s/interfaces", deviceID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list interfaces: %v", err)), nil
 		}
@@ -55,12 +59,6 @@ func makeListInterfacesHandler(client *kentik.Client) server.ToolHandlerFunc {
 	}
 }
 
-type deviceEntry struct {
-	ID         string `json:"id"`
-	DeviceName string `json:"device_name"`
-	Status     string `json:"device_status"`
-}
-
 type deviceInterfaceResult struct {
 	DeviceID   string          `json:"device_id"`
 	DeviceName string          `json:"device_name"`
@@ -70,26 +68,16 @@ type deviceInterfaceResult struct {
 
 func makeListAllInterfacesHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Step 1: Fetch all devices
-		devicesData, err := client.V5("GET", "/devices", nil)
+		// Step 1: Fetch all devices and apply the selector (active only)
+		devices, err := fetchDevices(ctx, client)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list devices: %v", err)), nil
 		}
-
-		var devicesResp struct {
-			Devices []deviceEntry `json:"devices"`
-		}
-		if err := json.Unmarshal(devicesData, &devicesResp); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse devices: %v", err)), nil
-		}
-
-		// Filter to active devices only
-		var activeDevices []deviceEntry
-		for _, d := range devicesResp.Devices {
-			if d.Status == "V" {
-				activeDevices = append(activeDevices, d)
-			}
+		resolution := selectorFromRequest(request).resolve(devices)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
 		}
+		activeDevices := resolution.Matched
 
 		// Step 2: Fetch interfaces for each device with concurrency limit
 		results := make([]deviceInterfaceResult, len(activeDevices))
@@ -98,7 +86,7 @@ func makeListAllInterfacesHandler(client *kentik.Client) server.ToolHandlerFunc
 
 		for i, device := range activeDevices {
 			wg.Add(1)
-			go func(idx int, dev deviceEntry) {
+			go func(idx int, dev inventoryDevice) {
 				defer wg.Done()
 				sem <- struct{}{}        // acquire
 				defer func() { <-sem }() // release
@@ -106,10 +94,10 @@ func makeListAllInterfacesHandler(client *kentik.Client) server.ToolHandlerFunc
 				// Small delay to stay under rate limits
 				time.Sleep(100 * time.Millisecond)
 
-				ifData, ifErr := client.V5("GET", fmt.Sprintf("/device/%s/interfaces", dev.ID), nil)
+				ifData, ifErr := client.V5(ctx, "GET", fmt.Sprintf("/device/%s/interfaces", dev.ID), nil)
 				results[idx] = deviceInterfaceResult{
 					DeviceID:   dev.ID,
-					DeviceName: dev.DeviceName,
+					DeviceName: dev.Name,
 				}
 				if ifErr != nil {
 					results[idx].Error = ifErr.Error()
@@ -138,7 +126,7 @@ func makeGetInterfaceHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V5("GET", fmt.Sprintf("/device/%s/interface/%s", deviceID, interfaceID), nil)
+		data, err := client.V5(ctx, "GET", fmt.Sprintf("/device/%s/interface/%s", deviceID, interfaceID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get interface: %v", err)), nil
 		}
@@ -12,11 +12,13 @@ import (
 func registerLabelTools(s *server.MCPServer, client *kentik.Client) {
 	listLabels := mcp.NewTool("kentik_list_labels",
 		mcp.WithDescription("List all device labels (tags used to group devices) in Kentik."),
+		readOnlyTool(),
 	)
 	s.AddTool(listLabels, makeListLabelsHandler(client))
 
 	getLabel := mcp.NewTool("kentik_get_label",
 		mcp.WithDescription("Get information about a specific device label by ID."),
+		readOnlyTool(),
 		mcp.WithString("label_id",
 			mcp.Required(),
 			mcp.Description("The ID of the label"),
@@ -27,7 +29,7 @@ func registerLabelTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeListLabelsHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		data, err := client.V5("GET", "/deviceLabels", nil)
+		data, err := client.V5(ctx, "GET", "/deviceLabels", nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list labels: %v", err)), nil
 		}
@@ -41,7 +43,7 @@ func makeGetLabelHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V5("GET", fmt.Sprintf("/deviceLabels/%s", labelID), nil)
+		data, err := client.V5(ctx, "GET", fmt.Sprintf("/deviceLabels/%s", labelID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get label: %v", err)), nil
 		}
@@ -0,0 +1,367 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// otherSite collects matrix keys that are not a known site (e.g. empty or
+// "Unknown" ultimate exit).
+const otherSite = "(other)"
+
+func registerMatrixTools(s *server.MCPServer, client *kentik.Client, opts Options) {
+	trafficMatrix := mcp.NewTool("kentik_traffic_matrix",
+		mcp.WithDescription("Site-to-site (east-west) traffic matrix. Queries flow data grouped by a source site dimension and a destination site dimension and renders an NxN matrix with row/column totals, the top site pairs, and asymmetric pairs. By default rows are the site of the device that saw the flow (i_device_site_name) and columns the site where traffic leaves the network (i_ult_exit_site)."),
+		readOnlyTool(),
+		mcp.WithString("metric",
+			mcp.Description("Metric: 'bytes' (default) or 'fps'."),
+		),
+		mcp.WithNumber("lookback_seconds",
+			mcp.Description(fmt.Sprintf("Time range. Default: %d", int(opts.lookback()))),
+		),
+		mcp.WithString("src_dimension",
+			mcp.Description("Dimension for the source site. Default: i_device_site_name"),
+		),
+		mcp.WithString("dst_dimension",
+			mcp.Description("Dimension for the destination site. Default: i_ult_exit_site (ultimate exit site). Use e.g. i_device_site_name with src/dst_connect_type='backbone' to restrict to backbone-facing interfaces."),
+		),
+		mcp.WithString("sites",
+			mcp.Description("Optional comma-separated site names (substring) to limit the matrix axes to. Traffic to/from other sites is folded into '(other)'."),
+		),
+		mcp.WithBoolean("include_intra_site",
+			mcp.Description("Include traffic that stays within one site (the diagonal) in totals and top pairs. Default: false"),
+		),
+		mcp.WithNumber("asymmetry_ratio",
+			mcp.Description("Flag site pairs whose A→B vs B→A ratio exceeds this. Default: 2"),
+		),
+		mcp.WithNumber("max_pairs",
+			mcp.Description("Number of site pairs to fetch (topx/depth). Default: 250"),
+		),
+		mcp.WithString("src_connect_type",
+			mcp.Description("Filter: source connectivity type (e.g. 'backbone')."),
+		),
+		mcp.WithString("dst_connect_type",
+			mcp.Description("Filter: destination connectivity type."),
+		),
+		mcp.WithString("filters_json",
+			mcp.Description("Optional raw JSON for complex filters."),
+		),
+		withDeviceSelector(),
+	)
+	s.AddTool(trafficMatrix, makeTrafficMatrixHandler(client))
+}
+
+// fetchSiteNames returns the names of all sites from /sites.
+func fetchSiteNames(ctx context.Context, client *kentik.Client) ([]string, error) {
+	data, err := cachedV5Get(ctx, client, "/sites", optionsFrom(ctx).SitesTTL)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Sites []struct {
+			Name string `json:"site_name"`
+		} `json:"sites"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse sites: %w", err)
+	}
+	names := make([]string, 0, len(resp.Sites))
+	for _, s := range resp.Sites {
+		if s.Name != "" {
+			names = append(names, s.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// splitDimensionKey returns the per-dimension values of a multi-dimension
+// topX entry. Kentik also joins them in "key" with " ---- ".
+func splitDimensionKey(entry map[string]interface{}, dims []string) []string {
+	vals := make([]string, len(dims))
+	parts := strings.Split(fmt.Sprintf("%v", entry["key"]), " ---- ")
+	for i, d := range dims {
+		if v, ok := entry[d]; ok && v != nil {
+			vals[i] = fmt.Sprintf("%v", v)
+		} else if i < len(parts) {
+			vals[i] = strings.TrimSpace(parts[i])
+		}
+	}
+	return vals
+}
+
+func makeTrafficMatrixHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		opts := optionsFrom(ctx)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
+
+		metric := "bytes"
+		if m, err := request.RequireString("metric"); err == nil && m != "" {
+			metric = m
+		}
+		lookback := opts.lookback()
+		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
+			lookback = lb
+		}
+		srcDim := "i_device_site_name"
+		if d, err := request.RequireString("src_dimension"); err == nil && d != "" {
+			srcDim = d
+		}
+		dstDim := "i_ult_exit_site"
+		if d, err := request.RequireString("dst_dimension"); err == nil && d != "" {
+			dstDim = d
+		}
+		ratioThreshold := 2.0
+		if r, err := request.RequireFloat("asymmetry_ratio"); err == nil && r > 1 {
+			ratioThreshold = r
+		}
+		maxPairs := 250.0
+		if mp, err := request.RequireFloat("max_pairs"); err == nil && mp > 0 {
+			maxPairs = mp
+		}
+		includeIntra := request.GetBool("include_intra_site", false)
+
+		knownSites, err := fetchSiteNames(ctx, client)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list sites: %v", err)), nil
+		}
+		sitesFilter, _ := request.RequireString("sites")
+		axisFilter := splitCSV(sitesFilter)
+
+		outsort := "avg_bits_per_sec"
+		valKey := "avg_bits_per_sec"
+		if metric == "fps" {
+			outsort = "avg_flows_per_sec"
+			valKey = "avg_flows_per_sec"
+		}
+
+		query := map[string]interface{}{
+			"metric":           metric,
+			"dimension":        []string{srcDim, dstDim},
+			"topx":             int(maxPairs),
+			"depth":            int(maxPairs),
+			"fastData":         "Auto",
+			"outsort":          outsort,
+			"lookback_seconds": int(lookback),
+			"time_format":      "UTC",
+			"hostname_lookup":  true,
+			"all_selected":     true,
+		}
+		if len(resolution.Devices) > 0 {
+			resolution.apply(query)
+		} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
+			query["device_name"] = dn
+			query["all_selected"] = false
+		}
+		if filtersObj := buildFilters(request); filtersObj != nil {
+			query["filters_obj"] = filtersObj
+		}
+
+		data, err := client.V5(ctx, "POST", "/query/topXdata", topXBody(query))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Query failed: %v", err)), nil
+		}
+		var resp struct {
+			Results []struct {
+				Data []map[string]interface{} `json:"data"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil || len(resp.Results) == 0 || len(resp.Results[0].Data) == 0 {
+			return mcp.NewToolResultText(resolution.note() + "No site-to-site traffic returned.\n\n" + formatJSON(data)), nil
+		}
+
+		// canonical maps a raw dimension value to a matrix axis label
+		canonical := func(v string) string {
+			site := ""
+			for _, k := range knownSites {
+				if strings.EqualFold(k, v) {
+					site = k
+					break
+				}
+			}
+			if site == "" {
+				return otherSite
+			}
+			if len(axisFilter) > 0 {
+				for _, f := range axisFilter {
+					if termMatches(site, f, false) {
+						return site
+					}
+				}
+				return otherSite
+			}
+			return site
+		}
+
+		cells := make(map[[2]string]float64)
+		axisSet := make(map[string]bool)
+		for _, e := range resp.Results[0].Data {
+			vals := splitDimensionKey(e, []string{srcDim, dstDim})
+			src, dst := canonical(vals[0]), canonical(vals[1])
+			v, _ := e[valKey].(float64)
+			cells[[2]string{src, dst}] += v
+			axisSet[src] = true
+			axisSet[dst] = true
+		}
+
+		var axis []string
+		for _, s := range knownSites {
+			if axisSet[s] {
+				axis = append(axis, s)
+			}
+		}
+		if axisSet[otherSite] {
+			axis = append(axis, otherSite)
+		}
+
+		rowTotals := make(map[string]float64)
+		colTotals := make(map[string]float64)
+		grand := 0.0
+		for k, v := range cells {
+			if k[0] == k[1] && !includeIntra {
+				continue
+			}
+			rowTotals[k[0]] += v
+			colTotals[k[1]] += v
+			grand += v
+		}
+
+		var sb strings.Builder
+		sb.WriteString(resolution.note())
+		sb.WriteString(fmt.Sprintf("## Traffic Matrix (%s → %s, %d sites)\n\n", srcDim, dstDim, len(axis)))
+		sb.WriteString(fmt.Sprintf("Rows: source (%s). Columns: destination (%s).", srcDim, dstDim))
+		if !includeIntra {
+			sb.WriteString(" Intra-site cells (diagonal) are shown in brackets and excluded from totals.")
+		}
+		sb.WriteString("\n\n")
+
+		// Matrix
+		sb.WriteString(fmt.Sprintf("| %-16s", "src \\ dst"))
+		for _, c := range axis {
+			sb.WriteString(fmt.Sprintf(" | %12s", truncateLabel(c, 12)))
+		}
+		sb.WriteString(fmt.Sprintf(" | %12s |\n", "**Total**"))
+		sb.WriteString("|" + strings.Repeat("-", 18))
+		for range axis {
+			sb.WriteString("|" + strings.Repeat("-", 14))
+		}
+		sb.WriteString("|" + strings.Repeat("-", 14) + "|\n")
+		for _, r := range axis {
+			sb.WriteString(fmt.Sprintf("| %-16s", truncateLabel(r, 16)))
+			for _, c := range axis {
+				v, ok := cells[[2]string{r, c}]
+				cell := "·"
+				if ok {
+					cell = formatRate(v, metric)
+					if r == c && !includeIntra {
+						cell = "[" + cell + "]"
+					}
+				}
+				sb.WriteString(fmt.Sprintf(" | %12s", cell))
+			}
+			sb.WriteString(fmt.Sprintf(" | %12s |\n", formatRate(rowTotals[r], metric)))
+		}
+		sb.WriteString(fmt.Sprintf("| %-16s", "**Total**"))
+		for _, c := range axis {
+			sb.WriteString(fmt.Sprintf(" | %12s", formatRate(colTotals[c], metric)))
+		}
+		sb.WriteString(fmt.Sprintf(" | %12s |\n\n", formatRate(grand, metric)))
+
+		// Top pairs
+		type pair struct {
+			src, dst string
+			v        float64
+		}
+		var pairs []pair
+		for k, v := range cells {
+			if k[0] == k[1] && !includeIntra {
+				continue
+			}
+			pairs = append(pairs, pair{k[0], k[1], v})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].v > pairs[j].v })
+
+		sb.WriteString("### Top Site Pairs\n\n")
+		sb.WriteString(fmt.Sprintf("| %-40s | %14s | %8s |\n", "Pair", "Avg", "% Total"))
+		sb.WriteString("|" + strings.Repeat("-", 42) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 10) + "|\n")
+		for i, p := range pairs {
+			if i >= 10 {
+				break
+			}
+			pct := 0.0
+			if grand > 0 {
+				pct = p.v / grand * 100
+			}
+			sb.WriteString(fmt.Sprintf("| %-40s | %14s | %7.1f%% |\n",
+				truncateLabel(p.src+" → "+p.dst, 40), formatRate(p.v, metric), pct))
+		}
+
+		// Asymmetry: compare each unordered pair once, ignoring tiny flows
+		type asym struct {
+			a, b       string
+			ab, ba     float64
+			ratio      float64
+			dominantAB bool
+		}
+		var asyms []asym
+		for i, a := range axis {
+			for _, b := range axis[i+1:] {
+				ab, ba := cells[[2]string{a, b}], cells[[2]string{b, a}]
+				if grand > 0 && (ab+ba)/grand < 0.01 {
+					continue
+				}
+				hi, lo := math.Max(ab, ba), math.Min(ab, ba)
+				ratio := math.Inf(1)
+				if lo > 0 {
+					ratio = hi / lo
+				}
+				if ratio >= ratioThreshold {
+					asyms = append(asyms, asym{a, b, ab, ba, ratio, ab >= ba})
+				}
+			}
+		}
+		sort.Slice(asyms, func(i, j int) bool { return asyms[i].ab+asyms[i].ba > asyms[j].ab+asyms[j].ba })
+
+		sb.WriteString(fmt.Sprintf("\n### Asymmetric Pairs (ratio ≥ %.1fx, ≥1%% of total)\n\n", ratioThreshold))
+		if len(asyms) == 0 {
+			sb.WriteString("None.\n")
+		} else {
+			for _, a := range asyms {
+				ratio := "one-way"
+				if !math.IsInf(a.ratio, 1) {
+					ratio = fmt.Sprintf("%.1fx", a.ratio)
+				}
+				dominant := a.a + " → " + a.b
+				if !a.dominantAB {
+					dominant = a.b + " → " + a.a
+				}
+				sb.WriteString(fmt.Sprintf("- ⚠️ %s ↔ %s: %s vs %s (%s, mostly %s)\n",
+					a.a, a.b, formatRate(a.ab, metric), formatRate(a.ba, metric), ratio, dominant))
+			}
+		}
+
+		if len(resp.Results[0].Data) >= int(maxPairs) {
+			sb.WriteString(fmt.Sprintf("\n*Result hit max_pairs=%d; smaller pairs may be missing.*\n", int(maxPairs)))
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// truncateLabel shortens s to n characters with a trailing ellipsis.
+func truncateLabel(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
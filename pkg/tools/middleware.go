@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/logging"
+	"github.com/awlx/kentik-mcp/pkg/metrics"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// LoggingMiddleware gives every tool call a trace ID, which the Kentik
+// client adds to its request logs, and logs the call's outcome.
+func LoggingMiddleware(logger *slog.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			traceID := logging.NewTraceID()
+			ctx = logging.WithTraceID(ctx, traceID)
+			log := logger.With("trace_id", traceID, "tool", request.Params.Name)
+			if args, err := json.Marshal(request.GetArguments()); err == nil {
+				log.Debug("tool call", "arguments", string(args))
+			}
+
+			start := time.Now()
+			result, err := next(ctx, request)
+			duration := time.Since(start).Milliseconds()
+			switch {
+			case err != nil:
+				log.Error("tool failed", "duration_ms", duration, "error", err.Error())
+			case result != nil && result.IsError:
+				log.Warn("tool returned error", "duration_ms", duration, "message", resultText(result))
+			default:
+				log.Info("tool done", "duration_ms", duration)
+			}
+			return result, err
+		}
+	}
+}
+
+// MetricsMiddleware counts tool calls by outcome and records their duration.
+func MetricsMiddleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+			outcome := "ok"
+			if err != nil {
+				outcome = "failed"
+			} else if result != nil && result.IsError {
+				outcome = "error"
+			}
+			metrics.ToolCalls.Inc(request.Params.Name, outcome)
+			metrics.ToolDuration.ObserveSince(start, request.Params.Name)
+			return result, err
+		}
+	}
+}
+
+// resultText returns the first text content of a result.
+func resultText(result *mcp.CallToolResult) string {
+	for _, c := range result.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			return tc.Text
+		}
+	}
+	return ""
+}
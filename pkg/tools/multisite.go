@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/awlx/kentik-mcp/pkg/kentik"
@@ -11,12 +13,18 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerMultiSiteTools(s *server.MCPServer, client *kentik.Client) {
+func registerMultiSiteTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	compareSites := mcp.NewTool("kentik_compare_sites",
-		mcp.WithDescription("Compare the same metric across multiple sites side-by-side. Runs the same query for each site and shows results in a comparison table. Useful for comparing traffic patterns, link utilization, or flow counts across different locations."),
+		mcp.WithDescription("Compare the same metric across multiple sites, device groups, or saved contexts side-by-side. Runs the same query for each group and shows either one table per group or a pivot table (keys × groups) with shares, totals and the group carrying the most of each key. Useful for comparing traffic patterns, link utilization, or flow counts across different locations."),
+		readOnlyTool(),
 		mcp.WithString("sites",
-			mcp.Required(),
-			mcp.Description("Comma-separated list of site names to compare. Each site's devices are auto-resolved."),
+			mcp.Description("Comma-separated list of site names to compare. Each site's devices are auto-resolved and intersected with the other device selectors (e.g. device_label='border'). At least one of sites, groups_json or contexts is required."),
+		),
+		mcp.WithString("groups_json",
+			mcp.Description("Arbitrary device groups to compare, as a JSON array of objects with a 'name' and any device selector fields. E.g. [{\"name\":\"AMS border\",\"site_name\":\"AMS\",\"device_label\":\"border\"},{\"name\":\"FRA core\",\"site_name\":\"FRA\",\"device_name_pattern\":\"core*\"}]"),
+		),
+		mcp.WithString("contexts",
+			mcp.Description("Comma-separated saved context names (see kentik_save_context). Each context's devices and filters form one group."),
 		),
 		mcp.WithString("dimension",
 			mcp.Required(),
@@ -26,25 +34,146 @@ func registerMultiSiteTools(s *server.MCPServer, client *kentik.Client) {
 			mcp.Description("Metric: 'bytes' (default) or 'fps'."),
 		),
 		mcp.WithNumber("lookback_seconds",
-			mcp.Description("Time range. Default: 3600"),
+			mcp.Description(fmt.Sprintf("Time range. Default: %d", int(opts.lookback()))),
 		),
 		mcp.WithNumber("topx",
-			mcp.Description("Number of results per site. Default: 5"),
+			mcp.Description("Number of results per group. Default: 5"),
 		),
 		mcp.WithString("dst_connect_type",
 			mcp.Description("Filter by destination connectivity type."),
 		),
+		mcp.WithString("output",
+			mcp.Description("'tables' (default): one table per group. 'pivot': one table with keys as rows and groups as columns."),
+		),
+		mcp.WithString("normalize",
+			mcp.Description("'none' (default) or 'per_device': divide each group's values by its device count, so groups of different size compare fairly."),
+		),
+		withDeviceSelector("site_name"),
 	)
 	s.AddTool(compareSites, makeCompareSitesHandler(client))
 }
 
+// compareGroup is one column of a comparison: a device selection plus the
+// request arguments used to build its filters.
+type compareGroup struct {
+	name       string
+	selector   deviceSelector
+	request    mcp.CallToolRequest
+	resolution *deviceResolution
+	queryIdx   int // index into the fan-out queries, -1 if not queried
+	entries    []map[string]interface{}
+	values     map[string]float64
+	total      float64
+}
+
+// withArguments returns a copy of request with overrides applied on top of
+// its arguments. Empty override values are ignored.
+func withArguments(request mcp.CallToolRequest, overrides map[string]string) mcp.CallToolRequest {
+	args := make(map[string]any)
+	for k, v := range request.GetArguments() {
+		args[k] = v
+	}
+	for k, v := range overrides {
+		if v != "" {
+			args[k] = v
+		}
+	}
+	out := request
+	out.Params.Arguments = args
+	return out
+}
+
+// argumentString renders a decoded JSON value the way a tool argument
+// would be passed as a string: numbers without exponent, lists as CSV and
+// objects as JSON. The request getters parse numbers and booleans back.
+func argumentString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, argumentString(item))
+		}
+		return strings.Join(parts, ",")
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// compareGroups builds the groups requested via sites, groups_json and contexts.
+func compareGroups(opts Options, request mcp.CallToolRequest) ([]*compareGroup, error) {
+	var groups []*compareGroup
+	base := selectorFromRequest(request)
+
+	sitesStr, _ := request.RequireString("sites")
+	for _, site := range splitCSV(sitesStr) {
+		sel := base
+		sel.Sites = []string{site}
+		groups = append(groups, &compareGroup{name: site, selector: sel, request: request})
+	}
+
+	if groupsJSON, _ := request.RequireString("groups_json"); strings.TrimSpace(groupsJSON) != "" {
+		var defs []map[string]any
+		if err := json.Unmarshal([]byte(groupsJSON), &defs); err != nil {
+			return nil, fmt.Errorf("invalid groups_json: %w", err)
+		}
+		for i, raw := range defs {
+			def := make(map[string]string, len(raw))
+			for k, v := range raw {
+				def[k] = argumentString(v)
+			}
+			name := def["name"]
+			if name == "" {
+				name = fmt.Sprintf("group %d", i+1)
+			}
+			delete(def, "name")
+			req := withArguments(request, def)
+			groups = append(groups, &compareGroup{name: name, selector: selectorFromRequest(req), request: req})
+		}
+	}
+
+	contextsStr, _ := request.RequireString("contexts")
+	for _, name := range splitCSV(contextsStr) {
+		qc := GetContext(opts.contextFile(), name)
+		if qc == nil {
+			return nil, fmt.Errorf("context '%s' not found; use kentik_list_contexts", name)
+		}
+		req := withArguments(request, map[string]string{
+			"device_name":      qc.DeviceNames,
+			"site_name":        qc.SiteName,
+			"device_label":     qc.DeviceLabel,
+			"dst_connect_type": qc.DstConnectType,
+			"src_connect_type": qc.SrcConnectType,
+			"port":             qc.Port,
+			"dst_as":           qc.DstAS,
+			"src_as":           qc.SrcAS,
+			"filters_json":     qc.FiltersJSON,
+		})
+		groups = append(groups, &compareGroup{name: qc.Name, selector: selectorFromRequest(req), request: req})
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("at least one of sites, groups_json or contexts is required")
+	}
+	return groups, nil
+}
+
 func makeCompareSitesHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		sitesStr, err := request.RequireString("sites")
+		opts := optionsFrom(ctx)
+		dimensionStr, err := request.RequireString("dimension")
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		dimensionStr, err := request.RequireString("dimension")
+		groups, err := compareGroups(opts, request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
@@ -53,7 +182,7 @@ func makeCompareSitesHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if m, err := request.RequireString("metric"); err == nil && m != "" {
 			metric = m
 		}
-		lookback := 3600.0
+		lookback := opts.lookback()
 		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
 			lookback = lb
 		}
@@ -61,36 +190,28 @@ func makeCompareSitesHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if tx, err := request.RequireFloat("topx"); err == nil {
 			topx = tx
 		}
+		output, _ := request.RequireString("output")
+		normalize, _ := request.RequireString("normalize")
+		perDevice := strings.EqualFold(normalize, "per_device")
 
 		outsort := "avg_bits_per_sec"
 		if metric == "fps" {
 			outsort = "avg_flows_per_sec"
 		}
 
-		sites := strings.Split(sitesStr, ",")
-		for i := range sites {
-			sites[i] = strings.TrimSpace(sites[i])
+		// Fetch the inventory once and resolve every group against it
+		devices, err := fetchDevices(ctx, client)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve groups: %v", err)), nil
 		}
 
-		var sb strings.Builder
-		sb.WriteString(fmt.Sprintf("## Site Comparison: %s\n\n", strings.Join(sites, " vs ")))
-
-		for _, site := range sites {
-			if site == "" {
-				continue
-			}
-
-			// Resolve devices for this site
-			devNames, resolveErr := resolveDevicesBySite(client, site)
-			if resolveErr != nil {
-				sb.WriteString(fmt.Sprintf("### %s — Error: %v\n\n", site, resolveErr))
-				continue
-			}
-			if len(devNames) == 0 {
-				sb.WriteString(fmt.Sprintf("### %s — No active devices found\n\n", site))
+		var queries []subQuery
+		for _, g := range groups {
+			g.queryIdx = -1
+			g.resolution = g.selector.resolve(devices)
+			if g.resolution.failed() {
 				continue
 			}
-
 			query := map[string]interface{}{
 				"metric":           metric,
 				"dimension":        []string{dimensionStr},
@@ -101,75 +222,203 @@ func makeCompareSitesHandler(client *kentik.Client) server.ToolHandlerFunc {
 				"lookback_seconds": int(lookback),
 				"time_format":      "UTC",
 				"hostname_lookup":  true,
-				"device_name":      strings.Join(devNames, ","),
+				"device_name":      strings.Join(g.resolution.Devices, ","),
 				"all_selected":     false,
 			}
-
-			// Apply filters
-			filtersObj := buildFilters(request)
-			if filtersObj != nil {
+			if filtersObj := buildFilters(g.request); filtersObj != nil {
 				query["filters_obj"] = filtersObj
 			}
+			g.queryIdx = len(queries)
+			queries = append(queries, subQuery{g.name, query})
+		}
 
-			body := map[string]interface{}{
-				"queries": []map[string]interface{}{
-					{"query": query, "bucket": "Left +Y Axis", "bucketIndex": 0, "isOverlay": false},
-				},
-			}
+		report := runQueries(ctx, client, queries)
+
+		// Detect value column
+		valKey := "avg_bits_per_sec"
+		if metric == "fps" {
+			valKey = "avg_flows_per_sec"
+		}
 
-			data, queryErr := client.V5("POST", "/query/topXdata", body)
-			if queryErr != nil {
-				sb.WriteString(fmt.Sprintf("### %s — Query failed: %v\n\n", site, queryErr))
+		for _, g := range groups {
+			if g.queryIdx < 0 || report.Results[g.queryIdx].Err != nil {
 				continue
 			}
-
 			var resp struct {
 				Results []struct {
 					Data []map[string]interface{} `json:"data"`
 				} `json:"results"`
 			}
-			if err := json.Unmarshal(data, &resp); err != nil || len(resp.Results) == 0 || len(resp.Results[0].Data) == 0 {
-				sb.WriteString(fmt.Sprintf("### %s (%d devices) — No data\n\n", site, len(devNames)))
+			if err := json.Unmarshal(report.Results[g.queryIdx].Data, &resp); err != nil || len(resp.Results) == 0 {
 				continue
 			}
+			g.entries = resp.Results[0].Data
+			g.values = make(map[string]float64)
+			for _, e := range g.entries {
+				v, _ := e[valKey].(float64)
+				if perDevice {
+					v /= float64(len(g.resolution.Devices))
+				}
+				g.values[fmt.Sprintf("%v", e["key"])] = v
+				g.total += v
+			}
+		}
 
-			entries := resp.Results[0].Data
-			sb.WriteString(fmt.Sprintf("### %s (%d devices)\n\n", site, len(devNames)))
+		names := make([]string, len(groups))
+		for i, g := range groups {
+			names[i] = g.name
+		}
 
-			// Detect value column
-			valKey := "avg_bits_per_sec"
-			if metric == "fps" {
-				valKey = "avg_flows_per_sec"
-			}
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## Site Comparison: %s\n\n", strings.Join(names, " vs ")))
+		sb.WriteString(report.status())
+		if perDevice {
+			sb.WriteString("*Values normalized per device.*\n\n")
+		}
 
-			total := 0.0
-			for _, e := range entries {
-				if v, ok := e[valKey].(float64); ok {
-					total += v
-				}
+		if strings.EqualFold(output, "pivot") {
+			writeComparePivot(&sb, groups, report, metric)
+		} else {
+			writeCompareTables(&sb, groups, report, metric)
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// groupProblem returns a heading suffix for a group without data, or "".
+func groupProblem(g *compareGroup, report *fanoutReport) string {
+	switch {
+	case g.queryIdx < 0:
+		return "Error\n\n" + g.resolution.errorText()
+	case report.Results[g.queryIdx].Err != nil:
+		return fmt.Sprintf("Query failed: %v", report.Results[g.queryIdx].Err)
+	case len(g.entries) == 0:
+		return "No data"
+	}
+	return ""
+}
+
+func writeCompareTables(sb *strings.Builder, groups []*compareGroup, report *fanoutReport, metric string) {
+	for _, g := range groups {
+		if g.queryIdx >= 0 {
+			sb.WriteString(g.resolution.note())
+		}
+		if problem := groupProblem(g, report); problem != "" {
+			sb.WriteString(fmt.Sprintf("### %s — %s\n\n", g.name, problem))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### %s (%d devices)\n\n", g.name, len(g.resolution.Devices)))
+
+		sb.WriteString(fmt.Sprintf("| %-45s | %14s | %8s |\n", "Key", "Avg", "% Total"))
+		sb.WriteString("|" + strings.Repeat("-", 47) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 10) + "|\n")
+
+		for _, e := range g.entries {
+			key := fmt.Sprintf("%v", e["key"])
+			v := g.values[key]
+			if len(key) > 45 {
+				key = key[:42] + "..."
+			}
+			pct := 0.0
+			if g.total > 0 {
+				pct = v / g.total * 100
 			}
+			sb.WriteString(fmt.Sprintf("| %-45s | %14s | %7.1f%% |\n",
+				key, formatRate(v, metric), pct))
+		}
+		sb.WriteString(fmt.Sprintf("| %-45s | %14s | %8s |\n",
+			"**Total**", formatRate(g.total, metric), "100%"))
+		sb.WriteString("\n")
+	}
+}
 
-			sb.WriteString(fmt.Sprintf("| %-45s | %14s | %8s |\n", "Key", "Avg", "% Total"))
-			sb.WriteString("|" + strings.Repeat("-", 47) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 10) + "|\n")
+// writeComparePivot renders keys as rows and groups as columns. A key
+// missing from a group's top-N shows as "—" rather than zero.
+func writeComparePivot(sb *strings.Builder, groups []*compareGroup, report *fanoutReport, metric string) {
+	var cols []*compareGroup
+	for _, g := range groups {
+		if problem := groupProblem(g, report); problem != "" {
+			sb.WriteString(fmt.Sprintf("> %s — %s\n", g.name, strings.ReplaceAll(problem, "\n\n", ": ")))
+			continue
+		}
+		sb.WriteString(g.resolution.note())
+		cols = append(cols, g)
+	}
+	if len(cols) == 0 {
+		sb.WriteString("\nNo group returned data.\n")
+		return
+	}
 
-			for _, e := range entries {
-				key := fmt.Sprintf("%v", e["key"])
-				if len(key) > 45 {
-					key = key[:42] + "..."
-				}
-				v, _ := e[valKey].(float64)
-				pct := 0.0
-				if total > 0 {
-					pct = v / total * 100
-				}
-				sb.WriteString(fmt.Sprintf("| %-45s | %14s | %7.1f%% |\n",
-					key, formatRate(v, metric), pct))
+	// Union of keys, ordered by combined value
+	sums := make(map[string]float64)
+	for _, g := range cols {
+		for k, v := range g.values {
+			sums[k] += v
+		}
+	}
+	keys := make([]string, 0, len(sums))
+	for k := range sums {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sums[keys[i]] != sums[keys[j]] {
+			return sums[keys[i]] > sums[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	sb.WriteString(fmt.Sprintf("\n| %-40s", "Key"))
+	for _, g := range cols {
+		name := g.name
+		if len(name) > 20 {
+			name = name[:17] + "..."
+		}
+		sb.WriteString(fmt.Sprintf(" | %20s | %6s", name, "Share"))
+	}
+	sb.WriteString(fmt.Sprintf(" | %-20s |\n", "Max"))
+	sb.WriteString("|" + strings.Repeat("-", 42))
+	for range cols {
+		sb.WriteString("|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 8))
+	}
+	sb.WriteString("|" + strings.Repeat("-", 22) + "|\n")
+
+	for _, k := range keys {
+		label := k
+		if len(label) > 40 {
+			label = label[:37] + "..."
+		}
+		sb.WriteString(fmt.Sprintf("| %-40s", label))
+		maxName, maxVal := "", -1.0
+		for _, g := range cols {
+			v, ok := g.values[k]
+			if !ok {
+				sb.WriteString(fmt.Sprintf(" | %20s | %6s", "—", ""))
+				continue
+			}
+			share := 0.0
+			if g.total > 0 {
+				share = v / g.total * 100
+			}
+			sb.WriteString(fmt.Sprintf(" | %20s | %5.1f%%", formatRate(v, metric), share))
+			if v > maxVal {
+				maxName, maxVal = g.name, v
 			}
-			sb.WriteString(fmt.Sprintf("| %-45s | %14s | %8s |\n",
-				"**Total**", formatRate(total, metric), "100%"))
-			sb.WriteString("\n")
 		}
+		sb.WriteString(fmt.Sprintf(" | %-20s |\n", maxName))
+	}
 
-		return mcp.NewToolResultText(sb.String()), nil
+	sb.WriteString(fmt.Sprintf("| %-40s", "**Total**"))
+	maxName, maxVal := "", -1.0
+	for _, g := range cols {
+		sb.WriteString(fmt.Sprintf(" | %20s | %6s", formatRate(g.total, metric), "100%"))
+		if g.total > maxVal {
+			maxName, maxVal = g.name, g.total
+		}
+	}
+	sb.WriteString(fmt.Sprintf(" | %-20s |\n", maxName))
+
+	sb.WriteString("\n| Group | Devices |\n|-------|---------|\n")
+	for _, g := range cols {
+		sb.WriteString(fmt.Sprintf("| %s | %d |\n", g.name, len(g.resolution.Devices)))
 	}
+	sb.WriteString("\n*— = key not in this group's top results.*\n")
 }
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCompareGroupsJSONValues(t *testing.T) {
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		"groups_json": `[{"name": "AMS", "site_name": "AMS", "topx": 5, "per_device": true, "device_label": ["border", "edge"]}, {"site_name": "FRA"}]`,
+	}
+	groups, err := compareGroups(Options{}, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	ams := groups[0]
+	if ams.name != "AMS" || groups[1].name != "group 2" {
+		t.Errorf("names = %q, %q", ams.name, groups[1].name)
+	}
+	if got := ams.request.GetInt("topx", 0); got != 5 {
+		t.Errorf("topx = %d, want 5", got)
+	}
+	if !ams.request.GetBool("per_device", false) {
+		t.Error("per_device = false, want true")
+	}
+	if got := ams.selector.Labels; len(got) != 2 || got[0] != "border" || got[1] != "edge" {
+		t.Errorf("labels = %v", got)
+	}
+}
+
+func TestArgumentString(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{nil, ""},
+		{"AMS", "AMS"},
+		{float64(15169), "15169"},
+		{float64(123456789), "123456789"},
+		{0.5, "0.5"},
+		{true, "true"},
+		{[]any{"a", float64(2)}, "a,2"},
+		{map[string]any{"k": "v"}, `{"k":"v"}`},
+	}
+	for _, tt := range tests {
+		if got := argumentString(tt.in); got != tt.want {
+			t.Errorf("argumentString(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
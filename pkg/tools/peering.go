@@ -0,0 +1,359 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerPeeringTools(s *server.MCPServer, client *kentik.Client) {
+	peeringAnalysis := mcp.NewTool("kentik_peering_analysis",
+		mcp.WithDescription("Peering opportunity analysis: ranks ASNs by the traffic exchanged with them over transit, and shows how much already flows over PNI/IX, so you can see which networks are worth peering with. Also lists the ASNs two and three hops down the transit AS paths, and optionally breaks the top candidates down per site."),
+		readOnlyTool(),
+		mcp.WithString("direction",
+			mcp.Description("'out' (default): traffic we send via transit, ranked by destination ASN. 'in': traffic we receive via transit, ranked by source ASN."),
+		),
+		mcp.WithNumber("lookback_seconds",
+			mcp.Description("Time range. Default: 86400 (24h)"),
+		),
+		mcp.WithNumber("topx",
+			mcp.Description("Number of candidate ASNs. Default: 20"),
+		),
+		mcp.WithString("transit_connect_types",
+			mcp.Description("Connectivity types counted as transit. Default: transit"),
+		),
+		mcp.WithString("peering_connect_types",
+			mcp.Description("Connectivity types counted as existing peering. Default: free_pni,ix"),
+		),
+		mcp.WithBoolean("include_path_hops",
+			mcp.Description("Also rank the second and third ASNs in the transit AS path. Default: true"),
+		),
+		mcp.WithBoolean("per_site",
+			mcp.Description("Break down the top candidates by site. Default: false"),
+		),
+		mcp.WithNumber("min_share",
+			mcp.Description("Hide candidates below this % of total transit traffic. Default: 0"),
+		),
+		mcp.WithString("rank_by",
+			mcp.Description("'volume' (default): average transit bps. 'p95': 95th percentile transit bps. 'share': % of the ASN's traffic still on transit rather than peering, then volume."),
+		),
+		withDeviceSelector(),
+	)
+	s.AddTool(peeringAnalysis, makePeeringAnalysisHandler(client))
+}
+
+var asnInKey = regexp.MustCompile(`\((\d+)\)\s*$`)
+
+// asnFromKey extracts the AS number from a topX key such as "GOOGLE (15169)".
+func asnFromKey(key string) string {
+	if m := asnInKey.FindStringSubmatch(key); m != nil {
+		return m[1]
+	}
+	return strings.TrimSpace(key)
+}
+
+// topXEntries returns the first result's rows of a topXdata response.
+func topXEntries(data json.RawMessage) []map[string]interface{} {
+	var resp struct {
+		Results []struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || len(resp.Results) == 0 {
+		return nil
+	}
+	return resp.Results[0].Data
+}
+
+func makePeeringAnalysisHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
+
+		direction := "out"
+		if d, err := request.RequireString("direction"); err == nil && strings.ToLower(d) == "in" {
+			direction = "in"
+		}
+		lookback := 86400.0
+		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
+			lookback = lb
+		}
+		topx := 20.0
+		if tx, err := request.RequireFloat("topx"); err == nil && tx > 0 {
+			topx = tx
+		}
+		transitTypes := "transit"
+		if v, err := request.RequireString("transit_connect_types"); err == nil && v != "" {
+			transitTypes = v
+		}
+		peeringTypes := "free_pni,ix"
+		if v, err := request.RequireString("peering_connect_types"); err == nil && v != "" {
+			peeringTypes = v
+		}
+		includeHops := request.GetBool("include_path_hops", true)
+		perSite := request.GetBool("per_site", false)
+		minShare := 0.0
+		if v, err := request.RequireFloat("min_share"); err == nil {
+			minShare = v
+		}
+		rankBy := "volume"
+		if v, err := request.RequireString("rank_by"); err == nil && v != "" {
+			rankBy = strings.ToLower(v)
+		}
+		// Share ranking reorders by peering already in place, so fetch a
+		// wider transit list to rank from
+		outsort, fetch := "avg_bits_per_sec", int(topx)
+		switch rankBy {
+		case "volume":
+		case "p95":
+			outsort = "p95th_bits_per_sec"
+		case "share":
+			fetch = max(fetch*3, 100)
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid rank_by '%s': use volume, p95 or share", rankBy)), nil
+		}
+
+		// Direction decides which side of the flow names the remote network
+		asDim, connectParam, secondDim, thirdDim := "AS_dst", "dst_connect_type", "dst_second_asn", "dst_third_asn"
+		if direction == "in" {
+			asDim, connectParam, secondDim, thirdDim = "AS_src", "src_connect_type", "src_second_asn", "src_third_asn"
+		}
+
+		mkQuery := func(dims []string, connectTypes string, n int, sortBy string) map[string]interface{} {
+			q := map[string]interface{}{
+				"metric":           "bytes",
+				"dimension":        dims,
+				"topx":             n,
+				"depth":            max(n*2, 100),
+				"fastData":         "Auto",
+				"outsort":          sortBy,
+				"lookback_seconds": int(lookback),
+				"time_format":      "UTC",
+				"hostname_lookup":  true,
+				"all_selected":     true,
+			}
+			if len(resolution.Devices) > 0 {
+				resolution.apply(q)
+			} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
+				q["device_name"] = dn
+				q["all_selected"] = false
+			}
+			req := withArguments(request, map[string]string{connectParam: connectTypes})
+			if filtersObj := buildFilters(req); filtersObj != nil {
+				q["filters_obj"] = filtersObj
+			}
+			return q
+		}
+
+		// The peered query is wider so candidates deep in the list still match
+		queries := []subQuery{
+			{"transit by ASN", mkQuery([]string{asDim}, transitTypes, fetch, outsort)},
+			{"peering by ASN", mkQuery([]string{asDim}, peeringTypes, 250, "avg_bits_per_sec")},
+			{"transit total", mkQuery([]string{"Traffic"}, transitTypes, 1, "avg_bits_per_sec")},
+		}
+		if includeHops {
+			queries = append(queries,
+				subQuery{"Transit AS Path: 2nd Hop", mkQuery([]string{secondDim}, transitTypes, 10, "avg_bits_per_sec")},
+				subQuery{"Transit AS Path: 3rd Hop", mkQuery([]string{thirdDim}, transitTypes, 10, "avg_bits_per_sec")},
+			)
+		}
+		siteIdx := -1
+		if perSite {
+			siteIdx = len(queries)
+			queries = append(queries, subQuery{"transit by ASN and site", mkQuery([]string{asDim, "i_device_site_name"}, transitTypes, 250, "avg_bits_per_sec")})
+		}
+
+		report := runQueries(ctx, client, queries)
+		if report.Results[0].Err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Transit query failed: %v", report.Results[0].Err)), nil
+		}
+
+		transit := topXEntries(report.Results[0].Data)
+		peered := make(map[string]float64)
+		for _, e := range topXEntries(report.Results[1].Data) {
+			v, _ := e["avg_bits_per_sec"].(float64)
+			peered[asnFromKey(fmt.Sprintf("%v", e["key"]))] += v
+		}
+		transitTotal := 0.0
+		for _, e := range topXEntries(report.Results[2].Data) {
+			v, _ := e["avg_bits_per_sec"].(float64)
+			transitTotal += v
+		}
+		if transitTotal == 0 {
+			// Fall back to the sum of the ranked rows when the total query failed
+			for _, e := range transit {
+				v, _ := e["avg_bits_per_sec"].(float64)
+				transitTotal += v
+			}
+		}
+
+		var sb strings.Builder
+		sb.WriteString(resolution.note())
+		verb := "sent to"
+		if direction == "in" {
+			verb = "received from"
+		}
+		sb.WriteString(fmt.Sprintf("## Peering Candidates (traffic %s ASNs via %s, last %s)\n\n", verb, transitTypes, formatLookback(lookback)))
+		sb.WriteString(report.status())
+		sb.WriteString(fmt.Sprintf("Total transit: **%s** avg. Existing peering types: %s. Ranked by %s.\n\n", formatBitsPerSec(transitTotal), peeringTypes, rankBy))
+
+		sb.WriteString(fmt.Sprintf("| %4s | %-40s | %12s | %12s | %7s | %10s | %-22s |\n",
+			"Rank", "ASN", "Transit Avg", "Transit P95", "Share", "On Transit", "Already peered (avg)"))
+		sb.WriteString("|" + strings.Repeat("-", 6) + "|" + strings.Repeat("-", 42) + "|" + strings.Repeat("-", 14) +
+			"|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 9) + "|" + strings.Repeat("-", 12) + "|" + strings.Repeat("-", 24) + "|\n")
+
+		ranked := rankPeeringCandidates(transit, peered, transitTotal, minShare, rankBy)
+		if len(ranked) > int(topx) {
+			ranked = ranked[:int(topx)]
+		}
+		var candidates []string
+		for i, c := range ranked {
+			candidates = append(candidates, c.key)
+			peeredCol := "no — candidate"
+			if c.peered > 0 {
+				peeredCol = "yes, " + formatBitsPerSec(c.peered)
+			}
+			sb.WriteString(fmt.Sprintf("| %4d | %-40s | %12s | %12s | %6.1f%% | %9.1f%% | %-22s |\n",
+				i+1, truncateLabel(c.key, 40), formatBitsPerSec(c.avg), formatBitsPerSec(c.p95), c.share, c.onTransit, peeredCol))
+		}
+		if len(ranked) == 0 {
+			sb.WriteString("\nNo transit traffic matched.\n")
+		}
+
+		if includeHops {
+			for _, idx := range []int{3, 4} {
+				r := report.Results[idx]
+				sb.WriteString(fmt.Sprintf("\n### %s\n\n", r.Label))
+				if r.Err != nil {
+					sb.WriteString(fmt.Sprintf("Query failed: %v\n", r.Err))
+					continue
+				}
+				for _, e := range topXEntries(r.Data) {
+					v, _ := e["avg_bits_per_sec"].(float64)
+					share := 0.0
+					if transitTotal > 0 {
+						share = v / transitTotal * 100
+					}
+					sb.WriteString(fmt.Sprintf("- %s: %s (%.1f%%)\n", fmt.Sprintf("%v", e["key"]), formatBitsPerSec(v), share))
+				}
+			}
+		}
+
+		if siteIdx >= 0 {
+			writePeeringSites(&sb, report.Results[siteIdx], asDim, candidates)
+		}
+
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// peeringCandidate is one transit ASN with the figures it is ranked by.
+type peeringCandidate struct {
+	key       string
+	avg, p95  float64
+	share     float64 // % of total transit traffic
+	onTransit float64 // % of the ASN's traffic carried by transit rather than peering
+	peered    float64
+}
+
+// rankPeeringCandidates drops rows below minShare and orders the rest by
+// rankBy: "volume" (avg), "p95" or "share" (on-transit %, then avg).
+func rankPeeringCandidates(transit []map[string]interface{}, peered map[string]float64, transitTotal, minShare float64, rankBy string) []peeringCandidate {
+	var out []peeringCandidate
+	for _, e := range transit {
+		c := peeringCandidate{key: fmt.Sprintf("%v", e["key"])}
+		c.avg, _ = e["avg_bits_per_sec"].(float64)
+		c.p95, _ = e["p95th_bits_per_sec"].(float64)
+		if transitTotal > 0 {
+			c.share = c.avg / transitTotal * 100
+		}
+		if c.share < minShare {
+			continue
+		}
+		c.peered = peered[asnFromKey(c.key)]
+		if c.avg+c.peered > 0 {
+			c.onTransit = c.avg / (c.avg + c.peered) * 100
+		}
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		switch rankBy {
+		case "p95":
+			return a.p95 > b.p95
+		case "share":
+			if a.onTransit != b.onTransit {
+				return a.onTransit > b.onTransit
+			}
+		}
+		return a.avg > b.avg
+	})
+	return out
+}
+
+// writePeeringSites renders, for the top 10 candidates, where their transit
+// traffic enters or leaves the network.
+func writePeeringSites(sb *strings.Builder, r subQueryResult, asDim string, candidates []string) {
+	sb.WriteString("\n### Per-Site Breakdown (top 10 candidates)\n\n")
+	if r.Err != nil {
+		sb.WriteString(fmt.Sprintf("Query failed: %v\n", r.Err))
+		return
+	}
+	bySite := make(map[string]map[string]float64)
+	for _, e := range topXEntries(r.Data) {
+		vals := splitDimensionKey(e, []string{asDim, "i_device_site_name"})
+		asn := asnFromKey(vals[0])
+		v, _ := e["avg_bits_per_sec"].(float64)
+		if bySite[asn] == nil {
+			bySite[asn] = make(map[string]float64)
+		}
+		bySite[asn][vals[1]] += v
+	}
+	for i, c := range candidates {
+		if i >= 10 {
+			break
+		}
+		sites := bySite[asnFromKey(c)]
+		if len(sites) == 0 {
+			continue
+		}
+		type siteVal struct {
+			name string
+			v    float64
+		}
+		var list []siteVal
+		total := 0.0
+		for n, v := range sites {
+			list = append(list, siteVal{n, v})
+			total += v
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].v > list[j].v })
+		var parts []string
+		for _, sv := range list {
+			parts = append(parts, fmt.Sprintf("%s %s (%.0f%%)", sv.name, formatBitsPerSec(sv.v), sv.v/total*100))
+		}
+		sb.WriteString(fmt.Sprintf("- **%s**: %s\n", c, strings.Join(parts, ", ")))
+	}
+}
+
+// formatLookback renders a lookback in seconds as e.g. "24h" or "90m".
+func formatLookback(seconds float64) string {
+	switch {
+	case seconds > 172800 && int(seconds)%86400 == 0:
+		return fmt.Sprintf("%dd", int(seconds)/86400)
+	case seconds >= 3600 && int(seconds)%3600 == 0:
+		return fmt.Sprintf("%dh", int(seconds)/3600)
+	case seconds >= 60:
+		return fmt.Sprintf("%dm", int(seconds)/60)
+	}
+	return fmt.Sprintf("%ds", int(seconds))
+}
@@ -11,9 +11,10 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
+func registerQueryTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	queryData := mcp.NewTool("kentik_query_data",
 		mcp.WithDescription("Query Kentik network flow data (topX). Returns JSON results with traffic metrics grouped by dimensions. Includes a human-readable summary table. Use lookback_seconds for relative time or starting_time/ending_time for absolute ranges."),
+		readOnlyTool(),
 		mcp.WithString("metric",
 			mcp.Required(),
 			mcp.Description("Unit of measure: bytes, in_bytes, out_bytes, packets, in_packets, out_packets, tcp_retransmit, fps, unique_src_ip, unique_dst_ip, client_latency, server_latency, appl_latency"),
@@ -32,7 +33,7 @@ func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
 				"TopFlow, Traffic"),
 		),
 		mcp.WithNumber("lookback_seconds",
-			mcp.Description("Look-back time in seconds (e.g. 3600 for last hour, 86400 for last day). Overrides starting_time/ending_time unless set to 0. Default: 3600"),
+			mcp.Description(fmt.Sprintf("Look-back time in seconds (e.g. 3600 for last hour, 86400 for last day). Overrides starting_time/ending_time unless set to 0. Default: %d", int(opts.lookback()))),
 		),
 		mcp.WithString("starting_time",
 			mcp.Description("Fixed start time in 'YYYY-MM-DD HH:mm:00' format. Only used when lookback_seconds is 0."),
@@ -40,14 +41,12 @@ func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
 		mcp.WithString("ending_time",
 			mcp.Description("Fixed end time in 'YYYY-MM-DD HH:mm:00' format. Only used when lookback_seconds is 0."),
 		),
-		mcp.WithString("device_name",
-			mcp.Description("Comma-delimited list of device names to query. Ignored if all_selected is true. Use kentik_search_devices to find device names."),
-		),
+		withDeviceSelector(),
 		mcp.WithBoolean("all_selected",
 			mcp.Description("Query against all devices. Default: true"),
 		),
 		mcp.WithNumber("topx",
-			mcp.Description("Number of top results to return (1-40). Default: 8"),
+			mcp.Description(fmt.Sprintf("Number of top results to return (1-40). Default: %d", int(opts.topX()))),
 		),
 		mcp.WithNumber("depth",
 			mcp.Description("Pool size from which topX is determined (25-250). Default: 100"),
@@ -82,34 +81,21 @@ func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
 		mcp.WithString("dst_as",
 			mcp.Description("Convenience filter: destination AS number."),
 		),
-		mcp.WithString("site_name",
-			mcp.Description("Convenience shortcut: auto-resolve devices by site name (e.g. 'NYC-DC1'). Searches for active devices at this site and uses them. Overrides device_name."),
-		),
-		mcp.WithString("device_label",
-			mcp.Description("Convenience shortcut: auto-resolve devices by label (e.g. 'border', 'core'). Searches for active devices with this label and uses them. Overrides device_name."),
-		),
 		mcp.WithString("fast_data",
 			mcp.Description("Dataset selection: Auto, Fast, or Full. Default: Auto"),
 		),
 	)
 	s.AddTool(queryData, makeQueryDataHandler(client))
 
-	// Compare tool: runs bytes + fps queries in parallel and shows skew
+	// Compare tool: runs bytes + fps queries concurrently and shows skew
 	queryCompare := mcp.NewTool("kentik_query_compare",
 		mcp.WithDescription("Compare traffic volume (bytes) vs flow rate (fps) for the same dimension and filters. Returns a combined table showing traffic %, flow %, and skew per row. Useful for identifying flow-heavy vs volume-heavy dimensions. Note: fps = flows per second (L3/L4 flow records), not HTTP requests."),
+		readOnlyTool(),
 		mcp.WithString("dimension",
 			mcp.Required(),
 			mcp.Description("Group-by dimension. E.g. Port_dst, AS_dst, IP_src, InterfaceID_dst, i_dst_connect_type_name"),
 		),
-		mcp.WithString("device_name",
-			mcp.Description("Comma-delimited list of device names to query."),
-		),
-		mcp.WithString("site_name",
-			mcp.Description("Auto-resolve devices by site name. Overrides device_name."),
-		),
-		mcp.WithString("device_label",
-			mcp.Description("Auto-resolve devices by label. Overrides device_name."),
-		),
+		withDeviceSelector(),
 		mcp.WithNumber("lookback_seconds",
 			mcp.Description("Look-back time in seconds. Default: 86400 (24h)"),
 		),
@@ -145,6 +131,7 @@ func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
 
 	queryURL := mcp.NewTool("kentik_query_url",
 		mcp.WithDescription("Generate a Kentik portal URL with Data Explorer configured for the given query parameters. Returns a URL that opens directly in the Kentik portal."),
+		readOnlyTool(),
 		mcp.WithString("metric",
 			mcp.Required(),
 			mcp.Description("Unit of measure: bytes, packets, etc."),
@@ -154,11 +141,9 @@ func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
 			mcp.Description("Group-by dimension(s), comma-separated"),
 		),
 		mcp.WithNumber("lookback_seconds",
-			mcp.Description("Look-back time in seconds. Default: 3600"),
-		),
-		mcp.WithString("device_name",
-			mcp.Description("Comma-delimited list of device names to query"),
+			mcp.Description(fmt.Sprintf("Look-back time in seconds. Default: %d", int(opts.lookback()))),
 		),
+		withDeviceSelector(),
 		mcp.WithBoolean("all_selected",
 			mcp.Description("Query against all devices. Default: true"),
 		),
@@ -166,7 +151,7 @@ func registerQueryTools(s *server.MCPServer, client *kentik.Client) {
 	s.AddTool(queryURL, makeQueryURLHandler(client))
 }
 
-func buildQueryObject(request mcp.CallToolRequest) (map[string]interface{}, error) {
+func buildQueryObject(opts Options, request mcp.CallToolRequest) (map[string]interface{}, error) {
 	metric, err := request.RequireString("metric")
 	if err != nil {
 		return nil, err
@@ -184,11 +169,11 @@ func buildQueryObject(request mcp.CallToolRequest) (map[string]interface{}, erro
 		}
 	}
 
-	lookback := 3600.0
+	lookback := opts.lookback()
 	if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
 		lookback = lb
 	}
-	topx := 8.0
+	topx := opts.topX()
 	if tx, err := request.RequireFloat("topx"); err == nil {
 		topx = tx
 	}
@@ -343,17 +328,17 @@ func buildFilters(request mcp.CallToolRequest) map[string]interface{} {
 
 func makeQueryDataHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		resolvedDevices := resolveDeviceShortcuts(client, request)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
 
-		query, err := buildQueryObject(request)
+		query, err := buildQueryObject(optionsFrom(ctx), request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		if resolvedDevices != "" {
-			query["device_name"] = resolvedDevices
-			query["all_selected"] = false
-		}
+		resolution.apply(query)
 
 		body := map[string]interface{}{
 			"queries": []map[string]interface{}{
@@ -366,99 +351,23 @@ func makeQueryDataHandler(client *kentik.Client) server.ToolHandlerFunc {
 			},
 		}
 
-		data, err := client.V5("POST", "/query/topXdata", body)
+		data, err := client.V5(ctx, "POST", "/query/topXdata", body)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to query data: %v", err)), nil
 		}
 
 		summary := summarizeQueryResults(data, query)
-		return mcp.NewToolResultText(summary), nil
-	}
-}
-
-// resolveDeviceShortcuts resolves site_name or device_label to device names.
-func resolveDeviceShortcuts(client *kentik.Client, request mcp.CallToolRequest) string {
-	if siteName, err := request.RequireString("site_name"); err == nil && siteName != "" {
-		names, _ := resolveDevicesBySite(client, siteName)
-		if len(names) > 0 {
-			return strings.Join(names, ",")
-		}
-	}
-	if label, err := request.RequireString("device_label"); err == nil && label != "" {
-		names, _ := resolveDevicesByLabel(client, label)
-		if len(names) > 0 {
-			return strings.Join(names, ",")
-		}
-	}
-	return ""
-}
-
-// resolveDevicesBySite fetches all devices and returns names matching the site.
-func resolveDevicesBySite(client *kentik.Client, siteName string) ([]string, error) {
-	data, err := client.V5("GET", "/devices", nil)
-	if err != nil {
-		return nil, err
-	}
-	var resp struct {
-		Devices []struct {
-			Name   string `json:"device_name"`
-			Status string `json:"device_status"`
-			Site   struct {
-				Name string `json:"site_name"`
-			} `json:"site"`
-		} `json:"devices"`
-	}
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, err
-	}
-	var names []string
-	siteNameLower := strings.ToLower(siteName)
-	for _, d := range resp.Devices {
-		if d.Status == "V" && strings.Contains(strings.ToLower(d.Site.Name), siteNameLower) {
-			names = append(names, d.Name)
-		}
-	}
-	return names, nil
-}
-
-// resolveDevicesByLabel fetches all devices and returns names matching the label.
-func resolveDevicesByLabel(client *kentik.Client, label string) ([]string, error) {
-	data, err := client.V5("GET", "/devices", nil)
-	if err != nil {
-		return nil, err
-	}
-	var resp struct {
-		Devices []struct {
-			Name   string `json:"device_name"`
-			Status string `json:"device_status"`
-			Labels []struct {
-				Name string `json:"name"`
-			} `json:"labels"`
-		} `json:"devices"`
-	}
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, err
-	}
-	var names []string
-	labelLower := strings.ToLower(label)
-	for _, d := range resp.Devices {
-		if d.Status != "V" {
-			continue
-		}
-		for _, l := range d.Labels {
-			if strings.Contains(strings.ToLower(l.Name), labelLower) {
-				names = append(names, d.Name)
-				break
-			}
-		}
+		return mcp.NewToolResultText(resolution.note() + summary), nil
 	}
-	return names, nil
 }
 
 // makeQueryCompareHandler runs bytes + fps queries and produces a skew table.
 func makeQueryCompareHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		resolvedDevices := resolveDeviceShortcuts(client, request)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
 
 		// Build base query for bytes
 		bytesQuery, err := buildCompareQuery(request, "bytes")
@@ -470,30 +379,19 @@ func makeQueryCompareHandler(client *kentik.Client) server.ToolHandlerFunc {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		if resolvedDevices != "" {
-			bytesQuery["device_name"] = resolvedDevices
-			bytesQuery["all_selected"] = false
-			fpsQuery["device_name"] = resolvedDevices
-			fpsQuery["all_selected"] = false
-		}
+		resolution.apply(bytesQuery)
+		resolution.apply(fpsQuery)
 
-		mkBody := func(q map[string]interface{}) map[string]interface{} {
-			return map[string]interface{}{
-				"queries": []map[string]interface{}{
-					{"query": q, "bucket": "Left +Y Axis", "bucketIndex": 0, "isOverlay": false},
-				},
-			}
-		}
-
-		// Run both queries
-		bytesData, err := client.V5("POST", "/query/topXdata", mkBody(bytesQuery))
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Bytes query failed: %v", err)), nil
-		}
-		fpsData, err := client.V5("POST", "/query/topXdata", mkBody(fpsQuery))
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("FPS query failed: %v", err)), nil
+		// Run both queries concurrently; one failing still leaves the other
+		report := runQueries(ctx, client, []subQuery{
+			{"bytes", bytesQuery},
+			{"fps", fpsQuery},
+		})
+		if report.failed() == len(report.Results) {
+			return mcp.NewToolResultError(fmt.Sprintf("Both queries failed: bytes: %v; fps: %v",
+				report.Results[0].Err, report.Results[1].Err)), nil
 		}
+		bytesData, fpsData := report.Results[0].Data, report.Results[1].Data
 
 		// Parse results
 		type resultRow struct {
@@ -565,16 +463,54 @@ func makeQueryCompareHandler(client *kentik.Client) server.ToolHandlerFunc {
 			}
 			rows = append(rows, row{k, bps, fps, bpct, fpct, fpct - bpct})
 		}
-		// Sort by bytes descending
+		// Sort by bytes descending, or by flows if the bytes query failed
+		bytesOK, fpsOK := report.Results[0].Err == nil, report.Results[1].Err == nil
+		sortVal := func(r row) float64 {
+			if bytesOK {
+				return r.Bps
+			}
+			return r.Fps
+		}
 		for i := 0; i < len(rows); i++ {
 			for j := i + 1; j < len(rows); j++ {
-				if rows[j].Bps > rows[i].Bps {
+				if sortVal(rows[j]) > sortVal(rows[i]) {
 					rows[i], rows[j] = rows[j], rows[i]
 				}
 			}
 		}
 
 		var sb strings.Builder
+		sb.WriteString(resolution.note())
+		sb.WriteString(report.status())
+		if !bytesOK || !fpsOK {
+			// Without both metrics there is no skew; show the one that succeeded
+			failed, res := "bytes", report.Results[0]
+			if bytesOK {
+				failed, res = "fps", report.Results[1]
+			}
+			sb.WriteString(fmt.Sprintf("## Volume vs Flows Comparison (%d keys): skew unavailable\n\n", len(rows)))
+			sb.WriteString(fmt.Sprintf("The %s query failed, so only the other metric is shown: %v\n\n", failed, res.Err))
+			label, unit := "Avg bps", "Vol %"
+			if !bytesOK {
+				label, unit = "Avg FPS", "Flow %"
+			}
+			sb.WriteString(fmt.Sprintf("| %-50s | %14s | %8s |\n", "Key", label, unit))
+			sb.WriteString("|" + strings.Repeat("-", 52) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 10) + "|\n")
+			for _, r := range rows {
+				value, pct := formatBitsPerSec(r.Bps), r.BytesPct
+				if !bytesOK {
+					value, pct = formatRate(r.Fps, "fps"), r.FpsPct
+				}
+				sb.WriteString(fmt.Sprintf("| %-50s | %14s | %7.1f%% |\n", truncateLabel(r.Key, 50), value, pct))
+			}
+			total := formatBitsPerSec(totalBytes)
+			if !bytesOK {
+				total = formatRate(totalFps, "fps")
+			}
+			sb.WriteString(fmt.Sprintf("| %-50s | %14s | %8s |\n", "**TOTAL**", total, "100.0%"))
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
 		sb.WriteString(fmt.Sprintf("## Volume vs Flows Comparison (%d keys)\n\n", len(rows)))
 		sb.WriteString(fmt.Sprintf("| %-50s | %14s | %8s | %10s | %8s | %8s |\n",
 			"Key", "Avg bps", "Vol %", "Avg FPS", "Flow %", "Skew"))
@@ -846,10 +782,16 @@ func formatBitsPerSec(bps float64) string {
 
 func makeQueryURLHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		query, err := buildQueryObject(request)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
+
+		query, err := buildQueryObject(optionsFrom(ctx), request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		resolution.apply(query)
 
 		query["viz_type"] = "stackedArea"
 
@@ -864,7 +806,7 @@ func makeQueryURLHandler(client *kentik.Client) server.ToolHandlerFunc {
 			},
 		}
 
-		data, err := client.V5("POST", "/query/url", body)
+		data, err := client.V5(ctx, "POST", "/query/url", body)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get query URL: %v", err)), nil
 		}
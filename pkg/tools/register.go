@@ -1,26 +1,249 @@
 package tools
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"time"
+
 	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/awlx/kentik-mcp/pkg/logging"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-// RegisterAll registers every Kentik tool on the given MCP server.
-func RegisterAll(s *server.MCPServer, client *kentik.Client) {
+// Options controls which tools RegisterAll registers and how their handlers
+// behave. Each server's handlers see the options it was registered with.
+type Options struct {
+	// EnableWrite registers tools that change state in Kentik (alarm
+	// acknowledgement, mitigations, synthetic tests). Off by default.
+	EnableWrite bool
+	// ReadOnly removes every tool not annotated as read-only, including
+	// local ones such as kentik_save_context. It overrides EnableWrite.
+	ReadOnly bool
+	// Allow and Deny are tool name globs (e.g. "kentik_synthetic*"). When
+	// Allow is set only matching tools are kept; Deny is applied after it.
+	Allow []string
+	Deny  []string
+
+	// DefaultLookback (seconds) and DefaultTopX replace the built-in query
+	// defaults of 3600 and 8 when set.
+	DefaultLookback int
+	DefaultTopX     int
+	// DevicesTTL and SitesTTL cache the inventory used by device selectors;
+	// zero disables caching.
+	DevicesTTL time.Duration
+	SitesTTL   time.Duration
+	// ContextFile overrides ~/.kentik-mcp-contexts.json. The audit log is
+	// kept in the same directory.
+	ContextFile string
+	// InterfaceClasses classify interfaces by description for the
+	// interface_class parameter of the interface tools.
+	InterfaceClasses []InterfaceClass
+	// AdvisorMaxWait is how long kentik_ai_advisor waits for an answer
+	// before returning the session ID. Default: 90s.
+	AdvisorMaxWait time.Duration
+	// Logger receives debug records from handlers, such as resolved device
+	// selections. Default: discard.
+	Logger *slog.Logger
+
+	// classes are the compiled InterfaceClasses, set by RegisterAll.
+	classes classSet
+}
+
+// InterfaceClass names a set of interfaces by a description regexp.
+// Provider, if set, extracts the provider name with its first capture group.
+type InterfaceClass struct {
+	Name        string
+	Description string
+	Provider    string
+}
+
+type optionsKey struct{}
+
+// withOptions makes opts available to a handler through its context.
+func withOptions(opts Options, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(context.WithValue(ctx, optionsKey{}, opts), request)
+	}
+}
+
+// optionsFrom returns the options the running tool was registered with, or
+// the zero Options outside a tool call.
+func optionsFrom(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	return opts
+}
+
+// Validate checks the allow and deny patterns and the interface classes.
+func (o Options) Validate() error {
+	for _, p := range append(append([]string{}, o.Allow...), o.Deny...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %v", p, err)
+		}
+	}
+	_, err := compileInterfaceClasses(o.InterfaceClasses)
+	return err
+}
+
+// RegisterAll registers every Kentik tool on the given MCP server and
+// returns the effective tool set after applying opts. The first account is
+// the primary one. With several accounts every Kentik tool gains an
+// account parameter.
+func RegisterAll(s *server.MCPServer, accounts []Account, opts Options) ([]mcp.Tool, error) {
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no Kentik account configured")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.classes, _ = compileInterfaceClasses(opts.InterfaceClasses)
+
+	if len(accounts) == 1 {
+		registerKentikTools(s, accounts[0].Client, opts)
+	} else {
+		registerMultiAccountTools(s, accounts, opts)
+	}
+	registerDimensionTools(s)
+	registerContextTools(s)
+	registerAdvisorConversationTools(s)
+	registerAccountTools(s, accounts)
+
+	var kept []server.ServerTool
+	var effective []mcp.Tool
+	for _, st := range s.ListTools() {
+		if opts.keep(st.Tool) {
+			kept = append(kept, server.ServerTool{Tool: st.Tool, Handler: withOptions(opts, st.Handler)})
+			effective = append(effective, st.Tool)
+		}
+	}
+	s.SetTools(kept...)
+	sort.Slice(effective, func(i, j int) bool { return effective[i].Name < effective[j].Name })
+	return effective, nil
+}
+
+// registerKentikTools registers the tools that call the Kentik API with
+// the given client.
+func registerKentikTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	registerDeviceTools(s, client)
 	registerInterfaceTools(s, client)
-	registerQueryTools(s, client)
-	registerTopTalkersTools(s, client)
-	registerMultiSiteTools(s, client)
-	registerCapacityPlanTools(s, client)
-	registerSNMPTools(s, client)
+	registerQueryTools(s, client, opts)
+	registerTopTalkersTools(s, client, opts)
+	registerMultiSiteTools(s, client, opts)
+	registerMatrixTools(s, client, opts)
+	registerPeeringTools(s, client)
+	registerCapacityPlanTools(s, client, opts)
+	registerSNMPTools(s, client, opts)
 	registerAlertingTools(s, client)
-	registerSyntheticsTools(s, client)
+	registerDDoSTools(s, client)
+	registerSyntheticsTools(s, client, opts)
+	registerSyntheticAgentTools(s, client)
+	registerSyntheticFlowTools(s, client, opts)
+	registerSyntheticSLATools(s, client, opts)
 	registerLabelTools(s, client)
 	registerSiteTools(s, client)
 	registerUserTools(s, client)
 	registerTagTools(s, client)
-	registerAIAdvisorTools(s, client)
-	registerDimensionTools(s)
-	registerContextTools(s)
+	registerAIAdvisorTools(s, client, opts)
+
+	if opts.EnableWrite && !opts.ReadOnly {
+		registerActionTools(s, client)
+		registerSyntheticActionTools(s, client)
+	}
+}
+
+// lookback returns the configured default lookback in seconds.
+func (o Options) lookback() float64 {
+	if o.DefaultLookback > 0 {
+		return float64(o.DefaultLookback)
+	}
+	return 3600
+}
+
+// topX returns the configured default number of rows.
+func (o Options) topX() float64 {
+	if o.DefaultTopX > 0 {
+		return float64(o.DefaultTopX)
+	}
+	return 8
+}
+
+// logger returns the configured logger.
+func (o Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return logging.Discard()
+}
+
+// keep reports whether a tool survives the read-only switch and the
+// allow/deny patterns.
+func (o Options) keep(t mcp.Tool) bool {
+	if o.ReadOnly && !isReadOnly(t) {
+		return false
+	}
+	if len(o.Allow) > 0 && !matchAny(o.Allow, t.Name) {
+		return false
+	}
+	return !matchAny(o.Deny, t.Name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isReadOnly(t mcp.Tool) bool {
+	return t.Annotations.ReadOnlyHint != nil && *t.Annotations.ReadOnlyHint
+}
+
+// ToolMode returns a short label for a tool's annotations, e.g. "read-only"
+// or "write, destructive".
+func ToolMode(t mcp.Tool) string {
+	a := t.Annotations
+	if isReadOnly(t) {
+		if a.OpenWorldHint != nil && !*a.OpenWorldHint {
+			return "read-only, local"
+		}
+		return "read-only"
+	}
+	mode := "write"
+	if a.OpenWorldHint != nil && !*a.OpenWorldHint {
+		mode += ", local"
+	}
+	if a.DestructiveHint == nil || *a.DestructiveHint {
+		mode += ", destructive"
+	}
+	return mode
+}
+
+// annotations sets all four MCP behaviour hints on a tool.
+func annotations(readOnly, destructive, idempotent, openWorld bool) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithReadOnlyHintAnnotation(readOnly)(t)
+		mcp.WithDestructiveHintAnnotation(destructive)(t)
+		mcp.WithIdempotentHintAnnotation(idempotent)(t)
+		mcp.WithOpenWorldHintAnnotation(openWorld)(t)
+	}
+}
+
+// readOnlyTool marks a tool that only reads from Kentik.
+func readOnlyTool() mcp.ToolOption {
+	return annotations(true, false, true, true)
+}
+
+// localTool marks a tool that only touches local state, such as saved contexts.
+func localTool(readOnly, destructive bool) mcp.ToolOption {
+	return annotations(readOnly, destructive, true, false)
+}
+
+// writeTool marks a tool that changes state in Kentik.
+func writeTool(destructive, idempotent bool) mcp.ToolOption {
+	return annotations(false, destructive, idempotent, true)
 }
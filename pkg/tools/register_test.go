@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestRegisterAllIsolatesOptions(t *testing.T) {
+	dir := t.TempDir()
+	accounts := []Account{{Name: "default", Client: kentik.NewClient(kentik.Config{Name: "default"})}}
+	paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	var servers []*server.MCPServer
+	for _, p := range paths {
+		s := server.NewMCPServer("test", "0")
+		if _, err := RegisterAll(s, accounts, Options{ContextFile: p}); err != nil {
+			t.Fatal(err)
+		}
+		servers = append(servers, s)
+	}
+
+	// The first server must still write to its own file after the second
+	// RegisterAll call.
+	for i, s := range servers {
+		save := s.GetTool("kentik_save_context")
+		res, err := save.Handler(context.Background(), toolRequest(map[string]any{"name": "ctx"}))
+		if err != nil || res.IsError {
+			t.Fatalf("server %d: save failed: %v %v", i, res, err)
+		}
+		if _, err := os.Stat(paths[i]); err != nil {
+			t.Errorf("server %d: %v", i, err)
+		}
+	}
+}
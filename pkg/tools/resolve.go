@@ -0,0 +1,503 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/awlx/kentik-mcp/pkg/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// inventoryDevice is the subset of a /devices entry used for resolution.
+type inventoryDevice struct {
+	ID          string `json:"id"`
+	Name        string `json:"device_name"`
+	Type        string `json:"device_type"`
+	Subtype     string `json:"device_subtype"`
+	Status      string `json:"device_status"`
+	SNMPIP      string `json:"device_snmp_ip"`
+	Description string `json:"device_description"`
+	Site        struct {
+		Name string `json:"site_name"`
+	} `json:"site"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+// deviceType returns the subtype when set, falling back to the type.
+func (d inventoryDevice) deviceType() string {
+	if d.Subtype != "" {
+		return d.Subtype
+	}
+	return d.Type
+}
+
+func (d inventoryDevice) labelNames() []string {
+	names := make([]string, 0, len(d.Labels))
+	for _, l := range d.Labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// fetchDevices returns the full device inventory.
+func fetchDevices(ctx context.Context, client *kentik.Client) ([]inventoryDevice, error) {
+	data, err := cachedV5Get(ctx, client, "/devices", optionsFrom(ctx).DevicesTTL)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Devices []inventoryDevice `json:"devices"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse devices: %w", err)
+	}
+	return resp.Devices, nil
+}
+
+// splitCSV splits a comma-separated parameter, dropping empty items.
+func splitCSV(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// withDeviceSelector adds the shared device selector parameters to a tool,
+// except those named in omit. All set criteria are intersected;
+// comma-separated values within one criterion are OR'd.
+func withDeviceSelector(omit ...string) mcp.ToolOption {
+	params := []struct {
+		name string
+		desc string
+	}{
+		{"device_name", "Comma-delimited list of device names. Combined with the other selectors, only these devices are considered. Use kentik_search_devices to find device names."},
+		{"site_name", "Select devices by site name (e.g. 'NYC-DC1'). Comma-separated for multiple sites."},
+		{"device_label", "Select devices by label (e.g. 'border', 'core'). Comma-separated for multiple labels."},
+		{"device_name_pattern", "Select devices whose name matches. Substring, or a glob when it contains * or ? (e.g. 'bdr*'). Comma-separated for multiple."},
+		{"device_type", "Select devices by type/subtype (e.g. 'router', 'host'). Comma-separated for multiple."},
+		{"exclude_devices", "Comma-delimited device names or globs to remove from the selection."},
+		{"match_mode", "How site, label, type and name patterns are matched: 'substring' (default, case-insensitive) or 'exact' (case-insensitive equality)."},
+	}
+	return func(t *mcp.Tool) {
+		for _, p := range params {
+			if slices.Contains(omit, p.name) {
+				continue
+			}
+			mcp.WithString(p.name, mcp.Description(p.desc))(t)
+		}
+	}
+}
+
+// deviceSelector is an intersection of device criteria. Within a criterion
+// any term may match; every non-empty criterion must match.
+type deviceSelector struct {
+	Devices         []string
+	Sites           []string
+	Labels          []string
+	Patterns        []string
+	Types           []string
+	Exclude         []string
+	Exact           bool
+	IncludeInactive bool
+}
+
+// selectorFromRequest reads the withDeviceSelector parameters.
+func selectorFromRequest(request mcp.CallToolRequest) deviceSelector {
+	get := func(name string) []string {
+		v, _ := request.RequireString(name)
+		return splitCSV(v)
+	}
+	mode, _ := request.RequireString("match_mode")
+	return deviceSelector{
+		Devices:  get("device_name"),
+		Sites:    get("site_name"),
+		Labels:   get("device_label"),
+		Patterns: get("device_name_pattern"),
+		Types:    get("device_type"),
+		Exclude:  get("exclude_devices"),
+		Exact:    strings.EqualFold(strings.TrimSpace(mode), "exact"),
+	}
+}
+
+// needsInventory reports whether the selector can only be evaluated
+// against the device list. A bare device_name list is passed through as-is.
+func (sel deviceSelector) needsInventory() bool {
+	return len(sel.Sites) > 0 || len(sel.Labels) > 0 || len(sel.Patterns) > 0 ||
+		len(sel.Types) > 0 || len(sel.Exclude) > 0
+}
+
+// describe renders the selector as e.g. "site_name 'AMS' ∩ device_label 'border'".
+func (sel deviceSelector) describe() string {
+	var parts []string
+	add := func(field string, terms []string) {
+		if len(terms) > 0 {
+			parts = append(parts, fmt.Sprintf("%s '%s'", field, strings.Join(terms, ",")))
+		}
+	}
+	add("device_name", sel.Devices)
+	add("site_name", sel.Sites)
+	add("device_label", sel.Labels)
+	add("device_name_pattern", sel.Patterns)
+	add("device_type", sel.Types)
+	s := strings.Join(parts, " ∩ ")
+	if s == "" {
+		s = "all devices"
+	}
+	if len(sel.Exclude) > 0 {
+		s += fmt.Sprintf(" − '%s'", strings.Join(sel.Exclude, ","))
+	}
+	return s
+}
+
+// selectorField is one criterion of a selector, evaluated per device.
+type selectorField struct {
+	name      string
+	terms     []string
+	exact     bool
+	ambiguous bool // several matched groups make a term ambiguous
+	groups    func(inventoryDevice) []string
+}
+
+func (sel deviceSelector) fields() []selectorField {
+	name := func(d inventoryDevice) []string { return []string{d.Name} }
+	var fields []selectorField
+	if len(sel.Devices) > 0 {
+		fields = append(fields, selectorField{"device_name", sel.Devices, true, false, name})
+	}
+	if len(sel.Sites) > 0 {
+		fields = append(fields, selectorField{"site_name", sel.Sites, sel.Exact, true, func(d inventoryDevice) []string {
+			if d.Site.Name == "" {
+				return nil
+			}
+			return []string{d.Site.Name}
+		}})
+	}
+	if len(sel.Labels) > 0 {
+		fields = append(fields, selectorField{"device_label", sel.Labels, sel.Exact, true, inventoryDevice.labelNames})
+	}
+	if len(sel.Patterns) > 0 {
+		fields = append(fields, selectorField{"device_name_pattern", sel.Patterns, sel.Exact, false, name})
+	}
+	if len(sel.Types) > 0 {
+		fields = append(fields, selectorField{"device_type", sel.Types, sel.Exact, false, func(d inventoryDevice) []string {
+			return []string{d.deviceType()}
+		}})
+	}
+	return fields
+}
+
+// resolveStatus classifies the outcome of a device selector resolution.
+type resolveStatus int
+
+const (
+	resolveNone      resolveStatus = iota // no selector that needs the inventory
+	resolveMatched                        // devices matched unambiguously
+	resolveAmbiguous                      // a site/label term matched several groups
+	resolveNoMatch                        // nothing (active) matched
+	resolveFailed                         // the device API call failed
+)
+
+// criterionMatch records what a single selector term matched on its own.
+type criterionMatch struct {
+	Field      string
+	Term       string
+	Groups     []string // distinct site/label/type/name values the term matched
+	Ambiguous  bool
+	Devices    int      // devices matching this term before intersection
+	NearMisses []string // closest values when the term matched nothing
+}
+
+// deviceResolution is the outcome of resolving a device selector to
+// concrete device names. Tools must surface it rather than silently falling
+// back to all_selected, which would query the whole network.
+type deviceResolution struct {
+	Status   resolveStatus
+	Selector deviceSelector
+	Criteria []criterionMatch
+	Matched  []inventoryDevice
+	Devices  []string // names of Matched
+	Inactive int      // devices matching the selector that are not active
+	Excluded int      // devices removed by exclude_devices
+	Err      error
+}
+
+// resolveDevices resolves the withDeviceSelector parameters of a request.
+func resolveDevices(ctx context.Context, client *kentik.Client, request mcp.CallToolRequest) *deviceResolution {
+	return resolveSelector(ctx, client, selectorFromRequest(request))
+}
+
+// resolveSelector fetches the inventory if the selector needs it and
+// resolves against it.
+func resolveSelector(ctx context.Context, client *kentik.Client, sel deviceSelector) *deviceResolution {
+	if !sel.needsInventory() {
+		return &deviceResolution{Status: resolveNone, Selector: sel}
+	}
+	devices, err := fetchDevices(ctx, client)
+	if err != nil {
+		return &deviceResolution{Status: resolveFailed, Selector: sel, Err: err}
+	}
+	res := sel.resolve(devices)
+	optionsFrom(ctx).logger().Debug("resolved device selector", "trace_id", logging.TraceID(ctx),
+		"devices", strings.Join(res.Devices, ","), "inactive", res.Inactive, "excluded", res.Excluded)
+	return res
+}
+
+// resolve evaluates the selector against an already fetched inventory.
+func (sel deviceSelector) resolve(devices []inventoryDevice) *deviceResolution {
+	res := &deviceResolution{Selector: sel}
+	fields := sel.fields()
+
+	// Per-term bookkeeping for reporting, independent of the intersection
+	for _, f := range fields {
+		all := make(map[string]bool)
+		for _, d := range devices {
+			for _, g := range f.groups(d) {
+				all[g] = true
+			}
+		}
+		for _, t := range f.terms {
+			cm := criterionMatch{Field: f.name, Term: t}
+			groups := make(map[string]bool)
+			for _, d := range devices {
+				hit := false
+				for _, g := range f.groups(d) {
+					if termMatches(g, t, f.exact) {
+						groups[g] = true
+						hit = true
+					}
+				}
+				if hit && (sel.IncludeInactive || d.Status == "V") {
+					cm.Devices++
+				}
+			}
+			for g := range groups {
+				cm.Groups = append(cm.Groups, g)
+			}
+			sort.Strings(cm.Groups)
+			cm.Ambiguous = f.ambiguous && len(cm.Groups) > 1
+			if len(cm.Groups) == 0 {
+				cm.NearMisses = nearMisses(t, all, 5)
+			}
+			res.Criteria = append(res.Criteria, cm)
+		}
+	}
+
+	for _, d := range devices {
+		if !sel.matches(d, fields) {
+			continue
+		}
+		if sel.excluded(d) {
+			res.Excluded++
+			continue
+		}
+		if !sel.IncludeInactive && d.Status != "V" {
+			res.Inactive++
+			continue
+		}
+		res.Matched = append(res.Matched, d)
+		res.Devices = append(res.Devices, d.Name)
+	}
+
+	res.Status = resolveMatched
+	if len(res.Matched) == 0 {
+		res.Status = resolveNoMatch
+	} else {
+		for _, cm := range res.Criteria {
+			if cm.Ambiguous {
+				res.Status = resolveAmbiguous
+				break
+			}
+		}
+	}
+	return res
+}
+
+func (sel deviceSelector) matches(d inventoryDevice, fields []selectorField) bool {
+	for _, f := range fields {
+		hit := false
+		for _, g := range f.groups(d) {
+			for _, t := range f.terms {
+				if termMatches(g, t, f.exact) {
+					hit = true
+					break
+				}
+			}
+			if hit {
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+func (sel deviceSelector) excluded(d inventoryDevice) bool {
+	for _, t := range sel.Exclude {
+		if termMatches(d.Name, t, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// termMatches compares case-insensitively. Terms containing glob
+// metacharacters are matched with path.Match; otherwise by equality
+// (exact) or substring.
+func termMatches(value, term string, exact bool) bool {
+	v, t := strings.ToLower(value), strings.ToLower(term)
+	if strings.ContainsAny(t, "*?[") {
+		ok, err := path.Match(t, v)
+		return err == nil && ok
+	}
+	if exact {
+		return v == t
+	}
+	return strings.Contains(v, t)
+}
+
+// nearMisses returns up to n candidate names closest to term by edit distance.
+func nearMisses(term string, candidates map[string]bool, n int) []string {
+	type scored struct {
+		name string
+		dist int
+	}
+	termLower := strings.ToLower(term)
+	var all []scored
+	for c := range candidates {
+		cLower := strings.ToLower(c)
+		d := levenshtein(termLower, cLower)
+		// Shared prefixes are a strong hint even when lengths differ a lot.
+		if strings.HasPrefix(cLower, termLower[:min(2, len(termLower))]) {
+			d--
+		}
+		all = append(all, scored{c, d})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].dist != all[j].dist {
+			return all[i].dist < all[j].dist
+		}
+		return all[i].name < all[j].name
+	})
+	var out []string
+	for i := 0; i < len(all) && i < n; i++ {
+		out = append(out, all[i].name)
+	}
+	return out
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// failed reports whether the resolution must abort the tool call.
+func (r *deviceResolution) failed() bool {
+	return r.Status == resolveNoMatch || r.Status == resolveFailed
+}
+
+// apply restricts query to the resolved devices. It is a no-op when the
+// selector did not need the inventory, so a bare device_name is left to
+// the query builder.
+func (r *deviceResolution) apply(query map[string]interface{}) {
+	if len(r.Devices) == 0 {
+		return
+	}
+	query["device_name"] = strings.Join(r.Devices, ",")
+	query["all_selected"] = false
+}
+
+// errorText explains a failed resolution, with per-term detail and
+// near-misses so the caller can fix the selector.
+func (r *deviceResolution) errorText() string {
+	if r.Status == resolveFailed {
+		return fmt.Sprintf("Failed to resolve devices (%s): %v", r.Selector.describe(), r.Err)
+	}
+
+	mode := "substring"
+	if r.Selector.Exact {
+		mode = "exact"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("No active devices match %s (%s match); refusing to query all devices.\n",
+		r.Selector.describe(), mode))
+	sb.WriteString(r.criteriaDetail())
+	if r.Inactive > 0 {
+		sb.WriteString(fmt.Sprintf("- %d matching devices are inactive\n", r.Inactive))
+	}
+	if r.Excluded > 0 {
+		sb.WriteString(fmt.Sprintf("- %d matching devices were removed by exclude_devices\n", r.Excluded))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (r *deviceResolution) criteriaDetail() string {
+	var sb strings.Builder
+	for _, cm := range r.Criteria {
+		if len(cm.Groups) == 0 {
+			sb.WriteString(fmt.Sprintf("- %s '%s' matched nothing", cm.Field, cm.Term))
+			if len(cm.NearMisses) > 0 {
+				sb.WriteString(fmt.Sprintf(". Did you mean: %s", strings.Join(cm.NearMisses, ", ")))
+			}
+			sb.WriteString("\n")
+			continue
+		}
+		groups := cm.Groups
+		more := ""
+		if len(groups) > 5 {
+			more = fmt.Sprintf(" +%d more", len(groups)-5)
+			groups = groups[:5]
+		}
+		sb.WriteString(fmt.Sprintf("- %s '%s' → %s%s (%d devices)\n",
+			cm.Field, cm.Term, strings.Join(groups, ", "), more, cm.Devices))
+	}
+	return sb.String()
+}
+
+// note is a short description of the resolution to prefix tool output
+// with, so the reader can see which devices the numbers cover.
+func (r *deviceResolution) note() string {
+	switch r.Status {
+	case resolveMatched:
+		return fmt.Sprintf("> Devices: %s → %d devices\n\n", r.Selector.describe(), len(r.Devices))
+	case resolveAmbiguous:
+		var amb []string
+		for _, cm := range r.Criteria {
+			if cm.Ambiguous {
+				amb = append(amb, fmt.Sprintf("%s '%s' matched %s", cm.Field, cm.Term, strings.Join(cm.Groups, ", ")))
+			}
+		}
+		return fmt.Sprintf("> ⚠️ Devices: %s → %d devices. Ambiguous: %s. Use match_mode=exact to pick one.\n\n",
+			r.Selector.describe(), len(r.Devices), strings.Join(amb, "; "))
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"ams", "", 3},
+		{"", "fra", 3},
+		{"ams1", "ams1", 0},
+		{"kitten", "sitting", 3},
+		{"border", "boarder", 1},
+		{"zürich", "zurich", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestTermMatches(t *testing.T) {
+	tests := []struct {
+		value, term string
+		exact       bool
+		want        bool
+	}{
+		{"AMS-DC1", "ams", false, true},
+		{"AMS-DC1", "ams", true, false},
+		{"AMS-DC1", "Ams-dc1", true, true},
+		{"bdr01.fra", "bdr*", false, true},
+		{"core01.fra", "bdr*", false, false},
+		{"bdr01.fra", "bdr0?.fra", true, true},
+		{"FRA", "ams", false, false},
+		{"anything", "[", false, false},
+	}
+	for _, tt := range tests {
+		if got := termMatches(tt.value, tt.term, tt.exact); got != tt.want {
+			t.Errorf("termMatches(%q, %q, %v) = %v, want %v", tt.value, tt.term, tt.exact, got, tt.want)
+		}
+	}
+}
+
+func TestNearMisses(t *testing.T) {
+	sites := map[string]bool{"AMS1": true, "AMS2": true, "FRA1": true, "NYC-DC1": true, "LON1": true}
+	tests := []struct {
+		term string
+		n    int
+		want []string
+	}{
+		{"AMS", 2, []string{"AMS1", "AMS2"}},
+		{"fra", 1, []string{"FRA1"}},
+		{"NYC", 1, []string{"NYC-DC1"}},
+		{"AMS", 0, nil},
+		{"x", 10, []string{"AMS1", "AMS2", "FRA1", "LON1", "NYC-DC1"}},
+	}
+	for _, tt := range tests {
+		if got := nearMisses(tt.term, sites, tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("nearMisses(%q, %d) = %v, want %v", tt.term, tt.n, got, tt.want)
+		}
+	}
+}
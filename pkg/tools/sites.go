@@ -12,11 +12,13 @@ import (
 func registerSiteTools(s *server.MCPServer, client *kentik.Client) {
 	listSites := mcp.NewTool("kentik_list_sites",
 		mcp.WithDescription("List all sites in Kentik. Sites are groups of devices based on geographic location."),
+		readOnlyTool(),
 	)
 	s.AddTool(listSites, makeListSitesHandler(client))
 
 	getSite := mcp.NewTool("kentik_get_site",
 		mcp.WithDescription("Get detailed information about a specific site by ID."),
+		readOnlyTool(),
 		mcp.WithString("site_id",
 			mcp.Required(),
 			mcp.Description("The ID of the site"),
@@ -27,7 +29,7 @@ func registerSiteTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeListSitesHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		data, err := client.V5("GET", "/sites", nil)
+		data, err := client.V5(ctx, "GET", "/sites", nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list sites: %v", err)), nil
 		}
@@ -41,7 +43,7 @@ func makeGetSiteHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V5("GET", fmt.Sprintf("/site/%s", siteID), nil)
+		data, err := client.V5(ctx, "GET", fmt.Sprintf("/site/%s", siteID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get site: %v", err)), nil
 		}
@@ -11,24 +11,20 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerSNMPTools(s *server.MCPServer, client *kentik.Client) {
+func registerSNMPTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	// Query interface utilization by SNMP counters
 	queryInterfaceTraffic := mcp.NewTool("kentik_get_interface_counters",
 		mcp.WithDescription("Query per-interface bandwidth utilization for specific devices. Uses flow data aggregated by interface to show per-link throughput. Useful for peering link utilization, transit capacity, and identifying hot interfaces. Filter by interface description to find specific link types."),
-		mcp.WithString("device_name",
-			mcp.Description("Comma-delimited list of device names to query."),
-		),
-		mcp.WithString("site_name",
-			mcp.Description("Auto-resolve devices by site name. Overrides device_name."),
-		),
-		mcp.WithString("device_label",
-			mcp.Description("Auto-resolve devices by label (e.g. 'border'). Overrides device_name."),
-		),
+		readOnlyTool(),
+		withDeviceSelector(),
 		mcp.WithString("interface_description_filter",
 			mcp.Description("Filter interfaces by description substring (case-insensitive). E.g. 'pni', 'transit', 'uplink', 'core'."),
 		),
+		mcp.WithString("interface_class",
+			mcp.Description("Only interfaces of this configured class, e.g. 'transit', 'pni', 'ix', 'core' (see interface_classes in the config file)."),
+		),
 		mcp.WithNumber("lookback_seconds",
-			mcp.Description("Look-back time in seconds. Default: 3600"),
+			mcp.Description(fmt.Sprintf("Look-back time in seconds. Default: %d", int(opts.lookback()))),
 		),
 		mcp.WithNumber("topx",
 			mcp.Description("Number of top interfaces to return. Default: 20"),
@@ -42,9 +38,13 @@ func registerSNMPTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		resolvedDevices := resolveDeviceShortcuts(client, request)
+		opts := optionsFrom(ctx)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
 
-		lookback := 3600.0
+		lookback := opts.lookback()
 		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
 			lookback = lb
 		}
@@ -59,22 +59,20 @@ func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerF
 		}
 
 		ifDescFilter, _ := request.RequireString("interface_description_filter")
-
-		// Build queries for egress and/or ingress
-		type queryResult struct {
-			label string
-			data  json.RawMessage
-			err   error
+		ifClass, _ := request.RequireString("interface_class")
+		if err := opts.classes.check(ifClass); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		var results []queryResult
+		// Build queries for egress and/or ingress
+		var queries []subQuery
 
 		buildQuery := func(dimension string) map[string]interface{} {
 			// Use large topx/depth when filtering by description to ensure we
 			// capture enough interfaces before post-filtering
 			queryTopx := int(topx)
 			queryDepth := int(topx * 2)
-			if ifDescFilter != "" {
+			if ifDescFilter != "" || ifClass != "" {
 				queryTopx = 250
 				queryDepth = 250
 			}
@@ -91,9 +89,8 @@ func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerF
 				"hostname_lookup":  true,
 				"all_selected":     true,
 			}
-			if resolvedDevices != "" {
-				q["device_name"] = resolvedDevices
-				q["all_selected"] = false
+			if len(resolution.Devices) > 0 {
+				resolution.apply(q)
 			} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
 				q["device_name"] = dn
 				q["all_selected"] = false
@@ -102,34 +99,26 @@ func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerF
 		}
 
 		if direction == "out" || direction == "both" {
-			q := buildQuery("InterfaceID_src")
-			body := map[string]interface{}{
-				"queries": []map[string]interface{}{
-					{"query": q, "bucket": "Left +Y Axis", "bucketIndex": 0, "isOverlay": false},
-				},
-			}
-			data, err := client.V5("POST", "/query/topXdata", body)
-			results = append(results, queryResult{"Egress (out)", data, err})
+			queries = append(queries, subQuery{"Egress (out)", buildQuery("InterfaceID_src")})
 		}
-
 		if direction == "in" || direction == "both" {
-			q := buildQuery("InterfaceID_dst")
-			body := map[string]interface{}{
-				"queries": []map[string]interface{}{
-					{"query": q, "bucket": "Left +Y Axis", "bucketIndex": 0, "isOverlay": false},
-				},
-			}
-			data, err := client.V5("POST", "/query/topXdata", body)
-			results = append(results, queryResult{"Ingress (in)", data, err})
+			queries = append(queries, subQuery{"Ingress (in)", buildQuery("InterfaceID_dst")})
 		}
+		if len(queries) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown direction '%s'. Valid: out, in, both", direction)), nil
+		}
+
+		report := runQueries(ctx, client, queries)
 
 		// Format results
 		var sb strings.Builder
+		sb.WriteString(resolution.note())
+		sb.WriteString(report.status())
 		filterLower := strings.ToLower(ifDescFilter)
 
-		for _, r := range results {
-			if r.err != nil {
-				sb.WriteString(fmt.Sprintf("## %s — Error: %v\n\n", r.label, r.err))
+		for _, r := range report.Results {
+			if r.Err != nil {
+				sb.WriteString(fmt.Sprintf("## %s — Error: %v\n\n", r.Label, r.Err))
 				continue
 			}
 
@@ -138,8 +127,8 @@ func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerF
 					Data []map[string]interface{} `json:"data"`
 				} `json:"results"`
 			}
-			if err := json.Unmarshal(r.data, &resp); err != nil || len(resp.Results) == 0 {
-				sb.WriteString(fmt.Sprintf("## %s — No data\n\n", r.label))
+			if err := json.Unmarshal(r.Data, &resp); err != nil || len(resp.Results) == 0 {
+				sb.WriteString(fmt.Sprintf("## %s — No data\n\n", r.Label))
 				continue
 			}
 
@@ -156,10 +145,13 @@ func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerF
 				}
 				entries = filtered
 			}
+			if ifClass != "" {
+				entries = opts.classes.filter(entries, ifClass)
+			}
 
-			sb.WriteString(fmt.Sprintf("## %s (%d interfaces)\n\n", r.label, len(entries)))
-			sb.WriteString(fmt.Sprintf("| %-70s | %14s | %14s | %14s |\n", "Interface", "Avg", "P95", "Max"))
-			sb.WriteString("|" + strings.Repeat("-", 72) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 16) + "|\n")
+			sb.WriteString(fmt.Sprintf("## %s (%d interfaces)\n\n", r.Label, len(entries)))
+			sb.WriteString(fmt.Sprintf("| %-70s | %-16s | %14s | %14s | %14s |\n", "Interface", "Class", "Avg", "P95", "Max"))
+			sb.WriteString("|" + strings.Repeat("-", 72) + "|" + strings.Repeat("-", 18) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 16) + "|" + strings.Repeat("-", 16) + "|\n")
 
 			avgKey := "avg_bits_per_sec"
 			p95Key := "p95th_bits_per_sec"
@@ -167,14 +159,15 @@ func makeQueryInterfaceTrafficHandler(client *kentik.Client) server.ToolHandlerF
 
 			for _, e := range entries {
 				key := fmt.Sprintf("%v", e["key"])
+				class := truncateLabel(opts.classes.label(key), 16)
 				if len(key) > 70 {
 					key = key[:67] + "..."
 				}
 				avg, _ := e[avgKey].(float64)
 				p95, _ := e[p95Key].(float64)
 				max, _ := e[maxKey].(float64)
-				sb.WriteString(fmt.Sprintf("| %-70s | %14s | %14s | %14s |\n",
-					key, formatBitsPerSec(avg), formatBitsPerSec(p95), formatBitsPerSec(max)))
+				sb.WriteString(fmt.Sprintf("| %-70s | %-16s | %14s | %14s | %14s |\n",
+					key, class, formatBitsPerSec(avg), formatBitsPerSec(p95), formatBitsPerSec(max)))
 			}
 			sb.WriteString("\n")
 		}
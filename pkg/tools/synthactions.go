@@ -0,0 +1,615 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerSyntheticActionTools registers the synthetic test lifecycle
+// tools. Like the other action tools they are only registered in write mode.
+func registerSyntheticActionTools(s *server.MCPServer, client *kentik.Client) {
+	createTest := mcp.NewTool("kentik_create_synthetic_test",
+		mcp.WithDescription("Create a synthetic test (ping/traceroute to IPs or a hostname, DNS, HTTP or page load). Without confirm it only returns a preview of the test definition and the selected agents. WRITE ACTION: requires confirm to repeat the test name, and is recorded in the local audit log."),
+		writeTool(false, false),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Test name"),
+		),
+		mcp.WithString("type",
+			mcp.Required(),
+			mcp.Description("Test type: 'ip' (ping/traceroute to IPs), 'hostname', 'dns', 'url' (HTTP) or 'page-load' (alias 'page_load')"),
+		),
+		mcp.WithString("target",
+			mcp.Required(),
+			mcp.Description("Target: comma-separated IPs for 'ip', a hostname for 'hostname' and 'dns', a URL for 'url' and 'page-load'"),
+		),
+		withAgentSelector(),
+		mcp.WithNumber("period",
+			mcp.Description("Seconds between test runs. Default: 60"),
+		),
+		mcp.WithString("ip_family",
+			mcp.Description("'dual' (default), 'v4' or 'v6'"),
+		),
+		mcp.WithBoolean("traceroute",
+			mcp.Description("Add a traceroute task to ip, hostname and url tests. Default: true for ip and hostname, false for url"),
+		),
+		mcp.WithString("ping_protocol",
+			mcp.Description("Ping protocol: 'icmp' (default), 'tcp' or 'udp'"),
+		),
+		mcp.WithNumber("ping_port",
+			mcp.Description("Port for tcp/udp ping. Default: 443 for tcp, 0 for icmp"),
+		),
+		mcp.WithString("dns_record_type",
+			mcp.Description("DNS record type for dns tests, e.g. A (default), AAAA, CNAME, MX, NS, TXT"),
+		),
+		mcp.WithString("dns_servers",
+			mcp.Description("Comma-separated DNS servers for dns tests. Default: 8.8.8.8"),
+		),
+		mcp.WithString("http_method",
+			mcp.Description("HTTP method for url tests. Default: GET"),
+		),
+		withHealthThresholds(),
+		mcp.WithString("confirm",
+			mcp.Description("Must equal name to create the test; omit to preview"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(createTest, makeCreateSyntheticTestHandler(client))
+
+	updateTest := mcp.NewTool("kentik_update_synthetic_test",
+		mcp.WithDescription("Change an existing synthetic test: name, target, agents, period or health thresholds. Only the given fields change. Without confirm it only returns a preview of the changes. WRITE ACTION: requires confirm to repeat the test ID, and is recorded in the local audit log."),
+		writeTool(false, true),
+		mcp.WithString("test_id",
+			mcp.Required(),
+			mcp.Description("ID of the test to change"),
+		),
+		mcp.WithString("name",
+			mcp.Description("New test name"),
+		),
+		mcp.WithString("target",
+			mcp.Description("New target, in the same form as for kentik_create_synthetic_test"),
+		),
+		withAgentSelector(),
+		mcp.WithNumber("period",
+			mcp.Description("Seconds between test runs"),
+		),
+		withHealthThresholds(),
+		mcp.WithString("confirm",
+			mcp.Description("Must equal test_id to apply the change; omit to preview"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(updateTest, makeUpdateSyntheticTestHandler(client))
+
+	setStatus := mcp.NewTool("kentik_set_synthetic_test_status",
+		mcp.WithDescription("Pause or resume a synthetic test. WRITE ACTION: requires confirm to repeat the test ID, and is recorded in the local audit log."),
+		writeTool(false, true),
+		mcp.WithString("test_id",
+			mcp.Required(),
+			mcp.Description("ID of the test"),
+		),
+		mcp.WithString("status",
+			mcp.Required(),
+			mcp.Description("'paused' or 'active'"),
+		),
+		mcp.WithString("confirm",
+			mcp.Required(),
+			mcp.Description("Must equal test_id to confirm the action"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(setStatus, makeSetSyntheticTestStatusHandler(client))
+
+	deleteTest := mcp.NewTool("kentik_delete_synthetic_test",
+		mcp.WithDescription("Delete a synthetic test and its configuration. WRITE ACTION: requires confirm to repeat the test ID, and is recorded in the local audit log."),
+		writeTool(true, true),
+		mcp.WithString("test_id",
+			mcp.Required(),
+			mcp.Description("ID of the test to delete"),
+		),
+		mcp.WithString("confirm",
+			mcp.Required(),
+			mcp.Description("Must equal test_id to confirm the action"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Reason recorded in the audit log"),
+		),
+	)
+	s.AddTool(deleteTest, makeDeleteSyntheticTestHandler(client))
+}
+
+// withAgentSelector adds the agent selection parameters. Agents matching
+// any of them are used.
+func withAgentSelector() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("agents",
+			mcp.Description("Comma-separated agent names (substring of the alias) or IDs"),
+		)(t)
+		mcp.WithString("agent_sites",
+			mcp.Description("Comma-separated site names; selects every agent at those sites"),
+		)(t)
+		mcp.WithString("agent_asns",
+			mcp.Description("Comma-separated ASNs; selects every agent in those networks"),
+		)(t)
+	}
+}
+
+// withHealthThresholds adds the warning/critical threshold parameters.
+func withHealthThresholds() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithNumber("latency_warning_ms", mcp.Description("Latency warning threshold in ms. Default on create: 100"))(t)
+		mcp.WithNumber("latency_critical_ms", mcp.Description("Latency critical threshold in ms. Default on create: 200"))(t)
+		mcp.WithNumber("loss_warning_pct", mcp.Description("Packet loss warning threshold in %. Default on create: 5"))(t)
+		mcp.WithNumber("loss_critical_pct", mcp.Description("Packet loss critical threshold in %. Default on create: 20"))(t)
+	}
+}
+
+// selectAgents resolves the withAgentSelector parameters. ok is false when
+// no selection was given. Terms that match nothing are errors.
+func selectAgents(ctx context.Context, client *kentik.Client, request mcp.CallToolRequest) (agents []syntheticAgent, ok bool, err error) {
+	names, _ := request.RequireString("agents")
+	sites, _ := request.RequireString("agent_sites")
+	asns, _ := request.RequireString("agent_asns")
+	if names == "" && sites == "" && asns == "" {
+		return nil, false, nil
+	}
+	all, err := fetchSyntheticAgents(ctx, client)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to list agents: %v", err)
+	}
+
+	selected := make(map[string]bool)
+	var missing []string
+	pick := func(term string, match func(a syntheticAgent) bool) {
+		hit := false
+		for _, a := range all {
+			if match(a) {
+				selected[a.ID] = true
+				hit = true
+			}
+		}
+		if !hit {
+			missing = append(missing, term)
+		}
+	}
+	for _, term := range splitCSV(names) {
+		lower := strings.ToLower(term)
+		pick(term, func(a syntheticAgent) bool {
+			return a.ID == term || strings.Contains(strings.ToLower(a.displayName()), lower)
+		})
+	}
+	for _, term := range splitCSV(sites) {
+		lower := strings.ToLower(term)
+		pick("site "+term, func(a syntheticAgent) bool {
+			return a.SiteName != "" && strings.Contains(strings.ToLower(a.SiteName), lower)
+		})
+	}
+	for _, term := range splitCSV(asns) {
+		asn, _ := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(term), "AS"))
+		pick("AS"+strconv.Itoa(asn), func(a syntheticAgent) bool { return asn != 0 && a.ASN == asn })
+	}
+	if len(missing) > 0 {
+		return nil, true, fmt.Errorf("no synthetic agent matches %s (see kentik_list_synthetic_agents)", strings.Join(missing, ", "))
+	}
+	for _, a := range all {
+		if selected[a.ID] {
+			agents = append(agents, a)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].displayName() < agents[j].displayName() })
+	return agents, true, nil
+}
+
+func agentIDs(agents []syntheticAgent) []string {
+	ids := make([]string, len(agents))
+	for i, a := range agents {
+		ids[i] = a.ID
+	}
+	return ids
+}
+
+// writeAgentTable lists selected agents.
+func writeAgentTable(sb *strings.Builder, agents []syntheticAgent) {
+	sb.WriteString(fmt.Sprintf("| %-12s | %-30s | %-25s | %8s | %-8s |\n", "ID", "Agent", "Site", "ASN", "Type"))
+	sb.WriteString("|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 27) +
+		"|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 10) + "|\n")
+	for _, a := range agents {
+		sb.WriteString(fmt.Sprintf("| %-12s | %-30s | %-25s | %8d | %-8s |\n",
+			a.ID, truncateLabel(a.displayName(), 30), truncateLabel(a.SiteName, 25), a.ASN, a.Type))
+	}
+}
+
+// syntheticTargetKey maps test types to their settings key.
+var syntheticTargetKey = map[string]string{
+	"ip":        "ip",
+	"hostname":  "hostname",
+	"dns":       "dns",
+	"url":       "url",
+	"page-load": "pageLoad",
+}
+
+// normalizeTestType accepts common spellings of the supported test types
+// and returns the API name.
+func normalizeTestType(t string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "ip", "ping":
+		return "ip", nil
+	case "hostname", "host":
+		return "hostname", nil
+	case "dns":
+		return "dns", nil
+	case "url", "http", "https":
+		return "url", nil
+	case "page_load", "pageload", "page-load":
+		return "page-load", nil
+	}
+	return "", fmt.Errorf("unsupported test type %q (ip, hostname, dns, url, page-load)", t)
+}
+
+// setTestTarget writes the target into the type-specific settings.
+func setTestTarget(settings map[string]interface{}, testType, target string) error {
+	key := syntheticTargetKey[testType]
+	sub, _ := settings[key].(map[string]interface{})
+	if sub == nil {
+		sub = make(map[string]interface{})
+		settings[key] = sub
+	}
+	if testType == "ip" {
+		targets := splitCSV(target)
+		if len(targets) == 0 {
+			return fmt.Errorf("target must list at least one IP")
+		}
+		sub["targets"] = targets
+		return nil
+	}
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return fmt.Errorf("target is required")
+	}
+	if (testType == "url" || testType == "page-load") && !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return fmt.Errorf("target for %s tests must be an http:// or https:// URL", testType)
+	}
+	sub["target"] = target
+	return nil
+}
+
+// applyThresholds sets the threshold parameters given in the request on
+// healthSettings and returns the names of those set.
+func applyThresholds(request mcp.CallToolRequest, health map[string]interface{}) []string {
+	var set []string
+	for param, key := range map[string]string{
+		"latency_warning_ms":  "latencyWarning",
+		"latency_critical_ms": "latencyCritical",
+		"loss_warning_pct":    "packetLossWarning",
+		"loss_critical_pct":   "packetLossCritical",
+	} {
+		if v, err := request.RequireFloat(param); err == nil && v >= 0 {
+			health[key] = v
+			if strings.HasPrefix(key, "latency") {
+				health["http"+strings.ToUpper(key[:1])+key[1:]] = v
+			}
+			set = append(set, param)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// buildSyntheticTest assembles a v202309 test definition from the create
+// parameters.
+func buildSyntheticTest(request mcp.CallToolRequest, agents []syntheticAgent) (map[string]interface{}, error) {
+	name, _ := request.RequireString("name")
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	typeParam, _ := request.RequireString("type")
+	testType, err := normalizeTestType(typeParam)
+	if err != nil {
+		return nil, err
+	}
+	target, _ := request.RequireString("target")
+
+	period := 60.0
+	if v, err := request.RequireFloat("period"); err == nil && v > 0 {
+		period = v
+	}
+	family := "IP_FAMILY_DUAL"
+	if v, _ := request.RequireString("ip_family"); v != "" {
+		switch strings.ToLower(v) {
+		case "dual":
+		case "v4", "ipv4", "4":
+			family = "IP_FAMILY_V4"
+		case "v6", "ipv6", "6":
+			family = "IP_FAMILY_V6"
+		default:
+			return nil, fmt.Errorf("ip_family must be dual, v4 or v6")
+		}
+	}
+
+	settings := map[string]interface{}{
+		"agentIds": agentIDs(agents),
+		"period":   int(period),
+		"family":   family,
+		"healthSettings": map[string]interface{}{
+			"latencyWarning":      100.0,
+			"latencyCritical":     200.0,
+			"packetLossWarning":   5.0,
+			"packetLossCritical":  20.0,
+			"httpLatencyWarning":  100.0,
+			"httpLatencyCritical": 200.0,
+		},
+	}
+	if err := setTestTarget(settings, testType, target); err != nil {
+		return nil, err
+	}
+	applyThresholds(request, settings["healthSettings"].(map[string]interface{}))
+
+	var tasks []string
+	switch testType {
+	case "ip", "hostname":
+		tasks = []string{"ping"}
+		if request.GetBool("traceroute", true) {
+			tasks = append(tasks, "traceroute")
+		}
+	case "dns":
+		tasks = []string{"dns"}
+		recordType := "A"
+		if v, _ := request.RequireString("dns_record_type"); v != "" {
+			recordType = strings.ToUpper(v)
+		}
+		servers := []string{"8.8.8.8"}
+		if v, _ := request.RequireString("dns_servers"); v != "" {
+			servers = splitCSV(v)
+		}
+		dns := settings["dns"].(map[string]interface{})
+		dns["recordType"] = "DNS_RECORD_" + recordType
+		dns["servers"] = servers
+		dns["timeout"] = 5000
+		dns["port"] = 53
+	case "url":
+		tasks = []string{"http"}
+		if request.GetBool("traceroute", false) {
+			tasks = append(tasks, "ping", "traceroute")
+		}
+		method := "GET"
+		if v, _ := request.RequireString("http_method"); v != "" {
+			method = strings.ToUpper(v)
+		}
+		url := settings["url"].(map[string]interface{})
+		url["method"] = method
+		url["timeout"] = 5000
+		url["headers"] = map[string]interface{}{}
+		url["ignoreTlsErrors"] = false
+	case "page-load":
+		tasks = []string{"page-load"}
+		pl := settings["pageLoad"].(map[string]interface{})
+		pl["timeout"] = 10000
+		pl["headers"] = map[string]interface{}{}
+		pl["ignoreTlsErrors"] = false
+	}
+	settings["tasks"] = tasks
+
+	for _, task := range tasks {
+		switch task {
+		case "ping":
+			protocol := "icmp"
+			if v, _ := request.RequireString("ping_protocol"); v != "" {
+				protocol = strings.ToLower(v)
+			}
+			port := 0.0
+			if protocol == "tcp" {
+				port = 443
+			}
+			if v, err := request.RequireFloat("ping_port"); err == nil {
+				port = v
+			}
+			settings["ping"] = map[string]interface{}{
+				"count": 5, "protocol": protocol, "port": int(port), "timeout": 3000, "delay": 0, "dscp": 0,
+			}
+		case "traceroute":
+			settings["trace"] = map[string]interface{}{
+				"count": 3, "protocol": "udp", "port": 33434, "timeout": 22500, "limit": 30, "delay": 0, "dscp": 0,
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"name":     strings.TrimSpace(name),
+		"type":     testType,
+		"status":   "TEST_STATUS_ACTIVE",
+		"settings": settings,
+	}, nil
+}
+
+func makeCreateSyntheticTestHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		agents, ok, err := selectAgents(ctx, client, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !ok || len(agents) == 0 {
+			return mcp.NewToolResultError("Select at least one agent with agents, agent_sites or agent_asns."), nil
+		}
+		test, err := buildSyntheticTest(request, agents)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		name := test["name"].(string)
+
+		confirm, _ := request.RequireString("confirm")
+		if confirm == "" {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("## Preview: new %s test %q\n\n", test["type"], name))
+			sb.WriteString(fmt.Sprintf("Agents (%d):\n\n", len(agents)))
+			writeAgentTable(&sb, agents)
+			def, _ := json.MarshalIndent(test, "", "  ")
+			sb.WriteString("\n```json\n" + string(def) + "\n```\n\n")
+			sb.WriteString(fmt.Sprintf("Nothing was created. To create this test, call again with the same parameters and confirm set to %q.\n", name))
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
+		comment, _ := request.RequireString("comment")
+		params := map[string]interface{}{"test": test, "comment": comment}
+		return runAction(ctx, client, "kentik_create_synthetic_test", name, confirm, params, func() (json.RawMessage, error) {
+			return client.V6(ctx, "POST", "/synthetics/v202309/tests", map[string]interface{}{"test": test})
+		})
+	}
+}
+
+func makeUpdateSyntheticTestHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		testID, err := requireTestID(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		data, err := client.V6(ctx, "GET", "/synthetics/v202309/tests/"+testID, nil)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic test: %v", err)), nil
+		}
+		var resp struct {
+			Test map[string]interface{} `json:"test"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil || resp.Test == nil {
+			return mcp.NewToolResultError("Failed to parse synthetic test"), nil
+		}
+		test := resp.Test
+		settings, _ := test["settings"].(map[string]interface{})
+		if settings == nil {
+			settings = make(map[string]interface{})
+			test["settings"] = settings
+		}
+
+		var changes []string
+		if v, _ := request.RequireString("name"); strings.TrimSpace(v) != "" {
+			changes = append(changes, fmt.Sprintf("name: %v → %s", test["name"], strings.TrimSpace(v)))
+			test["name"] = strings.TrimSpace(v)
+		}
+		if v, _ := request.RequireString("target"); v != "" {
+			testType := fmt.Sprintf("%v", test["type"])
+			if _, ok := syntheticTargetKey[testType]; !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("Changing the target of %s tests is not supported.", testType)), nil
+			}
+			old, _ := json.Marshal(settings[syntheticTargetKey[testType]])
+			if err := setTestTarget(settings, testType, v); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			changes = append(changes, fmt.Sprintf("target: %s → %s", old, v))
+		}
+		agents, ok, err := selectAgents(ctx, client, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if ok {
+			changes = append(changes, fmt.Sprintf("agents: %d → %d (%s)", len(listOf(settings["agentIds"])), len(agents), strings.Join(agentIDs(agents), ", ")))
+			settings["agentIds"] = agentIDs(agents)
+		}
+		if v, err := request.RequireFloat("period"); err == nil && v > 0 {
+			changes = append(changes, fmt.Sprintf("period: %v → %d", settings["period"], int(v)))
+			settings["period"] = int(v)
+		}
+		health, _ := settings["healthSettings"].(map[string]interface{})
+		if health == nil {
+			health = make(map[string]interface{})
+		}
+		if set := applyThresholds(request, health); len(set) > 0 {
+			settings["healthSettings"] = health
+			changes = append(changes, "thresholds: "+strings.Join(set, ", "))
+		}
+		if len(changes) == 0 {
+			return mcp.NewToolResultError("Nothing to change: set name, target, agents, period or a threshold."), nil
+		}
+
+		confirm, _ := request.RequireString("confirm")
+		if confirm == "" {
+			var sb strings.Builder
+			sb.WriteString(fmt.Sprintf("## Preview: changes to test %s (%v)\n\n", testID, test["name"]))
+			for _, c := range changes {
+				sb.WriteString("- " + c + "\n")
+			}
+			sb.WriteString(fmt.Sprintf("\nNothing was changed. To apply, call again with the same parameters and confirm set to %q.\n", testID))
+			return mcp.NewToolResultText(sb.String()), nil
+		}
+
+		comment, _ := request.RequireString("comment")
+		params := map[string]interface{}{"changes": changes, "comment": comment}
+		return runAction(ctx, client, "kentik_update_synthetic_test", testID, confirm, params, func() (json.RawMessage, error) {
+			return client.V6(ctx, "PUT", "/synthetics/v202309/tests/"+testID, map[string]interface{}{"test": test})
+		})
+	}
+}
+
+// requireTestID returns the numeric test_id parameter.
+func requireTestID(request mcp.CallToolRequest) (string, error) {
+	testID, _ := request.RequireString("test_id")
+	testID = strings.TrimSpace(testID)
+	if testID == "" {
+		return "", fmt.Errorf("test_id is required")
+	}
+	if _, err := strconv.ParseUint(testID, 10, 64); err != nil {
+		return "", fmt.Errorf("test_id must be a numeric test ID, got %q", testID)
+	}
+	return testID, nil
+}
+
+// listOf returns v as a slice, or nil.
+func listOf(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func makeSetSyntheticTestStatusHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		testID, err := requireTestID(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		statusParam, _ := request.RequireString("status")
+		var status string
+		switch strings.ToLower(statusParam) {
+		case "paused", "pause":
+			status = "TEST_STATUS_PAUSED"
+		case "active", "resume", "resumed":
+			status = "TEST_STATUS_ACTIVE"
+		default:
+			return mcp.NewToolResultError("status must be 'paused' or 'active'"), nil
+		}
+		confirm, _ := request.RequireString("confirm")
+		comment, _ := request.RequireString("comment")
+
+		params := map[string]interface{}{"status": status, "comment": comment}
+		return runAction(ctx, client, "kentik_set_synthetic_test_status", testID, confirm, params, func() (json.RawMessage, error) {
+			return client.V6(ctx, "PUT", "/synthetics/v202309/tests/"+testID+"/status", map[string]interface{}{"id": testID, "status": status})
+		})
+	}
+}
+
+func makeDeleteSyntheticTestHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		testID, err := requireTestID(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		confirm, _ := request.RequireString("confirm")
+		comment, _ := request.RequireString("comment")
+
+		params := map[string]interface{}{"comment": comment}
+		return runAction(ctx, client, "kentik_delete_synthetic_test", testID, confirm, params, func() (json.RawMessage, error) {
+			return client.V6(ctx, "DELETE", "/synthetics/v202309/tests/"+testID, nil)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package tools
+
+import "testing"
+
+func TestNormalizeTestType(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ping", "ip"},
+		{"HTTPS", "url"},
+		{"page_load", "page-load"},
+		{"pageload", "page-load"},
+		{"page-load", "page-load"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTestType(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeTestType(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if _, ok := syntheticTargetKey[got]; !ok {
+			t.Errorf("no target key for %q", got)
+		}
+	}
+	if _, err := normalizeTestType("bgp_monitor"); err == nil {
+		t.Error("normalizeTestType(bgp_monitor) succeeded")
+	}
+}
+
+func TestRequireTestID(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+		ok   bool
+	}{
+		{"1234", "1234", true},
+		{" 1234 ", "1234", true},
+		{"", "", false},
+		{"12/../34", "", false},
+		{"-1", "", false},
+	}
+	for _, tt := range tests {
+		got, err := requireTestID(toolRequest(map[string]any{"test_id": tt.in}))
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("requireTestID(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
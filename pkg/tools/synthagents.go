@@ -0,0 +1,304 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// syntheticAgent is the subset of a v202309 agent used by the tools.
+type syntheticAgent struct {
+	ID         string          `json:"id"`
+	Alias      string          `json:"alias"`
+	Name       string          `json:"name"`
+	SiteName   string          `json:"siteName"`
+	ASN        int             `json:"asn"`
+	Type       string          `json:"type"`
+	Status     string          `json:"status"`
+	Version    string          `json:"version"`
+	LastAuthed string          `json:"lastAuthed"`
+	Family     string          `json:"family"`
+	IP         string          `json:"ip"`
+	City       string          `json:"city"`
+	Region     string          `json:"region"`
+	Country    string          `json:"country"`
+	TestIDs    []string        `json:"testIds"`
+	Labels     json.RawMessage `json:"labels"`
+}
+
+// displayName returns the agent's alias, or its name if it has none.
+func (a syntheticAgent) displayName() string {
+	return firstNonEmpty(a.Alias, a.Name)
+}
+
+// labelNames accepts labels as strings or as objects with a name.
+func (a syntheticAgent) labelNames() []string {
+	var names []string
+	var asStrings []string
+	if json.Unmarshal(a.Labels, &asStrings) == nil {
+		return asStrings
+	}
+	var asObjects []struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(a.Labels, &asObjects) == nil {
+		for _, l := range asObjects {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+func (a syntheticAgent) private() bool {
+	return !strings.Contains(strings.ToLower(a.Type), "global")
+}
+
+func (a syntheticAgent) location() string {
+	var parts []string
+	for _, p := range []string{a.City, a.Country} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lastSeen returns the time since the agent last authenticated.
+func (a syntheticAgent) lastSeen(now time.Time) (time.Duration, bool) {
+	t, err := time.Parse(time.RFC3339, a.LastAuthed)
+	if err != nil || t.Year() < 2000 {
+		return 0, false
+	}
+	return now.Sub(t), true
+}
+
+func fetchSyntheticAgents(ctx context.Context, client *kentik.Client) ([]syntheticAgent, error) {
+	data, err := client.V6(ctx, "GET", "/synthetics/v202309/agents", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Agents []syntheticAgent `json:"agents"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse agents: %w", err)
+	}
+	return resp.Agents, nil
+}
+
+// compareVersions compares dotted numeric versions such as "1.2.10";
+// non-numeric suffixes are ignored.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(p[:end])
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+func registerSyntheticAgentTools(s *server.MCPServer, client *kentik.Client) {
+	fleet := mcp.NewTool("kentik_synthetic_agent_fleet",
+		mcp.WithDescription("Health report for synthetic agents (private by default): status, version, time since last seen, site, ASN, location, IP family and number of tests per agent, followed by the agents that are offline or outdated."),
+		readOnlyTool(),
+		mcp.WithString("agent_type",
+			mcp.Description("'private' (default), 'global' or 'all'"),
+		),
+		mcp.WithString("site",
+			mcp.Description("Only agents whose site name contains this (comma-separated for several)"),
+		),
+		mcp.WithString("label",
+			mcp.Description("Only agents with one of these labels (comma-separated)"),
+		),
+		mcp.WithString("min_version",
+			mcp.Description("Agents below this version are outdated. Default: the newest version in the fleet"),
+		),
+		mcp.WithNumber("offline_after_minutes",
+			mcp.Description("Agents not seen for this long are offline even if their status is OK. Default: 10"),
+		),
+	)
+	s.AddTool(fleet, makeSyntheticAgentFleetHandler(client))
+}
+
+func makeSyntheticAgentFleetHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		all, err := fetchSyntheticAgents(ctx, client)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list synthetic agents: %v", err)), nil
+		}
+
+		agentType := "private"
+		if v, _ := request.RequireString("agent_type"); v != "" {
+			agentType = strings.ToLower(v)
+		}
+		if agentType != "private" && agentType != "global" && agentType != "all" {
+			return mcp.NewToolResultError("agent_type must be private, global or all"), nil
+		}
+		siteFilter, _ := request.RequireString("site")
+		labelFilter, _ := request.RequireString("label")
+		offlineAfter := 10 * time.Minute
+		if v, err := request.RequireFloat("offline_after_minutes"); err == nil && v > 0 {
+			offlineAfter = time.Duration(v * float64(time.Minute))
+		}
+
+		var agents []syntheticAgent
+		for _, a := range all {
+			if (agentType == "private" && !a.private()) || (agentType == "global" && a.private()) {
+				continue
+			}
+			if sites := splitCSV(siteFilter); len(sites) > 0 && !containsAny(a.SiteName, sites) {
+				continue
+			}
+			if labels := splitCSV(labelFilter); len(labels) > 0 && !hasLabel(a.labelNames(), labels) {
+				continue
+			}
+			agents = append(agents, a)
+		}
+		if len(agents) == 0 {
+			return mcp.NewToolResultText("No synthetic agents match the filters."), nil
+		}
+
+		minVersion, _ := request.RequireString("min_version")
+		if minVersion == "" {
+			// The newest version among agents of the same type, before site
+			// and label filters
+			for _, a := range all {
+				if (agentType == "all" || a.private() == (agentType == "private")) && compareVersions(a.Version, minVersion) > 0 {
+					minVersion = a.Version
+				}
+			}
+		}
+
+		now := time.Now()
+		offline := func(a syntheticAgent) string {
+			var why []string
+			if a.Status != "" && a.Status != "AGENT_STATUS_OK" {
+				why = append(why, "status "+strings.ToLower(strings.TrimPrefix(a.Status, "AGENT_STATUS_")))
+			}
+			if age, ok := a.lastSeen(now); ok && age > offlineAfter {
+				why = append(why, "not seen for "+formatAge(age))
+			}
+			return strings.Join(why, ", ")
+		}
+		outdated := func(a syntheticAgent) bool {
+			return minVersion != "" && a.Version != "" && compareVersions(a.Version, minVersion) < 0
+		}
+
+		sort.Slice(agents, func(i, j int) bool {
+			oi, oj := offline(agents[i]) != "", offline(agents[j]) != ""
+			if oi != oj {
+				return oi
+			}
+			return agents[i].displayName() < agents[j].displayName()
+		})
+
+		var offlineList, outdatedList []string
+		var table strings.Builder
+		table.WriteString(fmt.Sprintf("| %-25s | %-8s | %-10s | %12s | %-20s | %8s | %-18s | %-6s | %5s |\n",
+			"Agent", "Status", "Version", "Last Seen", "Site", "ASN", "Location", "Family", "Tests"))
+		table.WriteString("|" + strings.Repeat("-", 27) + "|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 12) +
+			"|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 22) + "|" + strings.Repeat("-", 10) +
+			"|" + strings.Repeat("-", 20) + "|" + strings.Repeat("-", 8) + "|" + strings.Repeat("-", 7) + "|\n")
+		for _, a := range agents {
+			status := "ok"
+			if why := offline(a); why != "" {
+				status = "OFFLINE"
+				offlineList = append(offlineList, fmt.Sprintf("- **%s** (%s, %s): %s", a.displayName(), a.SiteName, a.ID, why))
+			}
+			version := a.Version
+			if outdated(a) {
+				version += " ⚠"
+				outdatedList = append(outdatedList, fmt.Sprintf("- **%s** (%s): %s < %s", a.displayName(), a.ID, a.Version, minVersion))
+			}
+			seen := "—"
+			if age, ok := a.lastSeen(now); ok {
+				seen = formatAge(age) + " ago"
+			}
+			family := strings.ToLower(strings.TrimPrefix(a.Family, "IP_FAMILY_"))
+			table.WriteString(fmt.Sprintf("| %-25s | %-8s | %-10s | %12s | %-20s | %8d | %-18s | %-6s | %5d |\n",
+				truncateLabel(a.displayName(), 25), status, truncateLabel(version, 10), seen, truncateLabel(a.SiteName, 20),
+				a.ASN, truncateLabel(a.location(), 18), family, len(a.TestIDs)))
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## Synthetic Agent Fleet (%s): %d agents, %d offline, %d outdated\n\n",
+			agentType, len(agents), len(offlineList), len(outdatedList)))
+		if minVersion != "" {
+			sb.WriteString(fmt.Sprintf("Reference version: %s. Offline after %s without contact.\n\n", minVersion, formatDuration(offlineAfter)))
+		}
+		sb.WriteString(table.String())
+		if len(offlineList) > 0 {
+			sb.WriteString(fmt.Sprintf("\n### Offline (%d)\n\n%s\n", len(offlineList), strings.Join(offlineList, "\n")))
+		}
+		if len(outdatedList) > 0 {
+			sb.WriteString(fmt.Sprintf("\n### Outdated (%d)\n\n%s\n", len(outdatedList), strings.Join(outdatedList, "\n")))
+		}
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
+
+// formatAge is formatDuration with whole days for long durations.
+func formatAge(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return formatDuration(d)
+}
+
+// containsAny reports whether s contains any of the terms, ignoring case.
+func containsAny(s string, terms []string) bool {
+	s = strings.ToLower(s)
+	for _, t := range terms {
+		if strings.Contains(s, strings.ToLower(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLabel(labels, wanted []string) bool {
+	for _, l := range labels {
+		for _, w := range wanted {
+			if strings.EqualFold(l, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
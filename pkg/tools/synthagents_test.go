@@ -0,0 +1,27 @@
+package tools
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.2.9", "1.2.10", -1},
+		{"v1.3.0", "1.3.0", 0},
+		{"1.3", "1.3.0", 0},
+		{"1.3", "1.3.1", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.4.2-rc1", "1.4.2", 0},
+		{"1.4.2+build7", "1.4.3", -1},
+		{"", "0.0.1", -1},
+		{"unknown", "", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
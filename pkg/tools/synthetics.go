@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -10,14 +11,16 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerSyntheticsTools(s *server.MCPServer, client *kentik.Client) {
+func registerSyntheticsTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	listTests := mcp.NewTool("kentik_list_synthetic_tests",
 		mcp.WithDescription("List all configured synthetic tests in Kentik (active and paused). Returns test names, types, status, and configuration."),
+		readOnlyTool(),
 	)
 	s.AddTool(listTests, makeListSyntheticTestsHandler(client))
 
 	getTest := mcp.NewTool("kentik_get_synthetic_test",
 		mcp.WithDescription("Get detailed configuration and status for a specific synthetic test."),
+		readOnlyTool(),
 		mcp.WithString("test_id",
 			mcp.Required(),
 			mcp.Description("The ID of the synthetic test"),
@@ -26,29 +29,28 @@ func registerSyntheticsTools(s *server.MCPServer, client *kentik.Client) {
 	s.AddTool(getTest, makeGetSyntheticTestHandler(client))
 
 	getResults := mcp.NewTool("kentik_get_synthetic_results",
-		mcp.WithDescription("Get probe results for one or more synthetic tests over a given time period. Returns health status, latency, packet loss, and other metrics."),
+		mcp.WithDescription("Get probe results for one or more synthetic tests over a given time period. By default returns a health summary: per-test and per-agent tables (health, avg/p95 latency, jitter, loss, failing agent/target pairs), the pairs currently in warning or critical state, and the worst offenders. Set format to 'raw' for the full JSON."),
+		readOnlyTool(),
 		mcp.WithString("test_ids",
 			mcp.Required(),
 			mcp.Description("Comma-separated list of synthetic test IDs"),
 		),
-		mcp.WithString("start_time",
-			mcp.Required(),
-			mcp.Description("Start time in RFC3339 format (e.g. 2025-01-01T00:00:00Z)"),
-		),
-		mcp.WithString("end_time",
-			mcp.Required(),
-			mcp.Description("End time in RFC3339 format (e.g. 2025-01-01T01:00:00Z)"),
+		withWindowParams(opts),
+		mcp.WithString("format",
+			mcp.Description("'summary' (default) or 'raw' for the unprocessed results JSON"),
 		),
 	)
 	s.AddTool(getResults, makeGetSyntheticResultsHandler(client))
 
 	listAgents := mcp.NewTool("kentik_list_synthetic_agents",
 		mcp.WithDescription("List all synthetic monitoring agents available in the account (both global/public and private agents)."),
+		readOnlyTool(),
 	)
 	s.AddTool(listAgents, makeListSyntheticAgentsHandler(client))
 
 	getAgent := mcp.NewTool("kentik_get_synthetic_agent",
 		mcp.WithDescription("Get detailed information about a specific synthetic monitoring agent."),
+		readOnlyTool(),
 		mcp.WithString("agent_id",
 			mcp.Required(),
 			mcp.Description("The ID of the synthetic agent"),
@@ -57,18 +59,19 @@ func registerSyntheticsTools(s *server.MCPServer, client *kentik.Client) {
 	s.AddTool(getAgent, makeGetSyntheticAgentHandler(client))
 
 	getTrace := mcp.NewTool("kentik_get_synthetic_trace",
-		mcp.WithDescription("Get network trace (traceroute) data for a specific synthetic test. The test must have traceroute task configured."),
+		mcp.WithDescription("Analyze traceroute data for a synthetic test: per-agent paths (hops with IP, rDNS, ASN, latency and loss) and AS-level path summaries. With a baseline window it highlights changed hops, new ASNs and latency jumps. The test must have a traceroute task configured."),
+		readOnlyTool(),
 		mcp.WithString("test_id",
 			mcp.Required(),
 			mcp.Description("The ID of the synthetic test"),
 		),
-		mcp.WithString("start_time",
-			mcp.Required(),
-			mcp.Description("Start time in RFC3339 format"),
+		withWindowParams(opts),
+		withBaselineParams("paths"),
+		mcp.WithString("agents",
+			mcp.Description("Only these agents: comma-separated names (substring) or IDs"),
 		),
-		mcp.WithString("end_time",
-			mcp.Required(),
-			mcp.Description("End time in RFC3339 format"),
+		mcp.WithString("format",
+			mcp.Description("'summary' (default) or 'raw' for the unprocessed trace JSON"),
 		),
 	)
 	s.AddTool(getTrace, makeGetSyntheticTraceHandler(client))
@@ -76,7 +79,7 @@ func registerSyntheticsTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeListSyntheticTestsHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		data, err := client.V6("GET", "/synthetics/v202309/tests", nil)
+		data, err := client.V6(ctx, "GET", "/synthetics/v202309/tests", nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list synthetic tests: %v", err)), nil
 		}
@@ -90,7 +93,7 @@ func makeGetSyntheticTestHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V6("GET", fmt.Sprintf("/synthetics/v202309/tests/%s", testID), nil)
+		data, err := client.V6(ctx, "GET", fmt.Sprintf("/synthetics/v202309/tests/%s", testID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic test: %v", err)), nil
 		}
@@ -104,11 +107,7 @@ func makeGetSyntheticResultsHandler(client *kentik.Client) server.ToolHandlerFun
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		startTime, err := request.RequireString("start_time")
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
-		}
-		endTime, err := request.RequireString("end_time")
+		window, err := resolveWindow(ctx, request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
@@ -123,21 +122,33 @@ func makeGetSyntheticResultsHandler(client *kentik.Client) server.ToolHandlerFun
 
 		body := map[string]interface{}{
 			"testIds":   testIDs,
-			"startTime": startTime,
-			"endTime":   endTime,
+			"startTime": rfc3339(window.Start),
+			"endTime":   rfc3339(window.End),
 		}
 
-		data, err := client.V6("POST", "/synthetics/v202309/results", body)
+		data, err := client.V6(ctx, "POST", "/synthetics/v202309/results", body)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic results: %v", err)), nil
 		}
-		return mcp.NewToolResultText(formatJSON(data)), nil
+		if format, _ := request.RequireString("format"); format == "raw" {
+			return mcp.NewToolResultText(fmt.Sprintf("Window: %s\n\n%s", window, formatJSON(data))), nil
+		}
+
+		testNames, agentNames := syntheticNames(ctx, client)
+		summary, ok := summarizeSyntheticResults(data, testNames, agentNames)
+		if !ok {
+			return mcp.NewToolResultText(fmt.Sprintf("Window: %s\n\n%s", window, formatJSON(data))), nil
+		}
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("## Synthetic Test Health\n\nWindow: %s\n\n", window))
+		sb.WriteString(summary)
+		return mcp.NewToolResultText(sb.String()), nil
 	}
 }
 
 func makeListSyntheticAgentsHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		data, err := client.V6("GET", "/synthetics/v202309/agents", nil)
+		data, err := client.V6(ctx, "GET", "/synthetics/v202309/agents", nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list synthetic agents: %v", err)), nil
 		}
@@ -151,7 +162,7 @@ func makeGetSyntheticAgentHandler(client *kentik.Client) server.ToolHandlerFunc
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V6("GET", fmt.Sprintf("/synthetics/v202309/agents/%s", agentID), nil)
+		data, err := client.V6(ctx, "GET", fmt.Sprintf("/synthetics/v202309/agents/%s", agentID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic agent: %v", err)), nil
 		}
@@ -165,25 +176,50 @@ func makeGetSyntheticTraceHandler(client *kentik.Client) server.ToolHandlerFunc
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		startTime, err := request.RequireString("start_time")
+		window, err := resolveWindow(ctx, request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		endTime, err := request.RequireString("end_time")
+
+		baseline, compare, err := baselineWindow(request, window)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		body := map[string]interface{}{
-			"id":        testID,
-			"startTime": startTime,
-			"endTime":   endTime,
+		fetch := func(w timeWindow) (json.RawMessage, error) {
+			body := map[string]interface{}{
+				"id":        testID,
+				"startTime": rfc3339(w.Start),
+				"endTime":   rfc3339(w.End),
+			}
+			return client.V6(ctx, "POST", "/synthetics/v202309/trace", body)
 		}
-
-		data, err := client.V6("POST", "/synthetics/v202309/trace", body)
+		data, err := fetch(window)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic trace: %v", err)), nil
 		}
-		return mcp.NewToolResultText(formatJSON(data)), nil
+		if format, _ := request.RequireString("format"); format == "raw" {
+			return mcp.NewToolResultText(fmt.Sprintf("Window: %s\n\n%s", window, formatJSON(data))), nil
+		}
+		paths, ok := reconstructPaths(data)
+		if !ok {
+			return mcp.NewToolResultText(fmt.Sprintf("Window: %s\n\n%s", window, formatJSON(data))), nil
+		}
+
+		_, agentNames := syntheticNames(ctx, client)
+		agentFilter, _ := request.RequireString("agents")
+		paths = filterTracePaths(paths, agentFilter, agentNames)
+
+		var baseWindow *timeWindow
+		var basePaths []*tracePath
+		if compare {
+			baseData, err := fetch(baseline)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to get baseline trace: %v", err)), nil
+			}
+			basePaths, _ = reconstructPaths(baseData)
+			baseWindow = &baseline
+		}
+		return mcp.NewToolResultText(formatTraceAnalysis(window, paths, baseWindow, basePaths, agentNames)), nil
 	}
 }
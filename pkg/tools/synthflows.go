@@ -0,0 +1,530 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxCorrelationTargets caps the destination IPs in the flow filter.
+const maxCorrelationTargets = 32
+
+// flowBreakdowns are the per-destination flow views compared between the
+// windows. The first must be the total.
+var flowBreakdowns = []struct {
+	label string
+	dims  []string
+}{
+	{"Volume", []string{"Traffic"}},
+	{"Destination ASNs", []string{"AS_dst"}},
+	{"Connectivity", []string{"i_dst_connect_type_name"}},
+	{"Egress Interfaces", []string{"i_device_name", "InterfaceID_dst"}},
+	{"AS Paths", []string{"dst_bgp_aspath"}},
+}
+
+// breakdownIndex returns the position of a flow breakdown by label.
+func breakdownIndex(label string) int {
+	for i, b := range flowBreakdowns {
+		if b.label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// syntheticTestTargets returns the test's name and type and the target
+// hosts and IPs from its settings.
+func syntheticTestTargets(data json.RawMessage) (name, testType string, hosts, ips []string, err error) {
+	var resp struct {
+		Test struct {
+			Name     string                     `json:"name"`
+			Type     string                     `json:"type"`
+			Settings map[string]json.RawMessage `json:"settings"`
+		} `json:"test"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", "", nil, nil, fmt.Errorf("parse test: %w", err)
+	}
+	name, testType = resp.Test.Name, resp.Test.Type
+	var sub struct {
+		Target  string   `json:"target"`
+		Targets []string `json:"targets"`
+	}
+	if key, ok := syntheticTargetKey[testType]; ok {
+		_ = json.Unmarshal(resp.Test.Settings[key], &sub)
+	}
+	for _, t := range append(sub.Targets, sub.Target) {
+		t = strings.TrimSpace(t)
+		if u, err := url.Parse(t); err == nil && u.Host != "" {
+			t = u.Hostname()
+		}
+		switch {
+		case t == "":
+		case net.ParseIP(t) != nil:
+			ips = append(ips, t)
+		default:
+			hosts = append(hosts, t)
+		}
+	}
+	return name, testType, hosts, ips, nil
+}
+
+// syntheticTaskTotals aggregates results per task type and collects the
+// destination IPs the agents measured. DNS tasks report the resolver as
+// destination, so their IPs are left out.
+func syntheticTaskTotals(data json.RawMessage) (map[string]*synthSamples, []string) {
+	var res synthResults
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, nil
+	}
+	totals := make(map[string]*synthSamples)
+	var dstIPs []string
+	seen := make(map[string]bool)
+	for _, row := range res.Results {
+		for _, a := range row.Agents {
+			for _, t := range a.Tasks {
+				for kind, r := range map[string]*synthTaskResult{"ping": t.Ping, "http": t.HTTP, "dns": t.DNS} {
+					if r == nil {
+						continue
+					}
+					if totals[kind] == nil {
+						totals[kind] = &synthSamples{}
+					}
+					totals[kind].add(r, t.Health)
+					if kind != "dns" && net.ParseIP(r.DstIP) != nil && !seen[r.DstIP] {
+						seen[r.DstIP] = true
+						dstIPs = append(dstIPs, r.DstIP)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(dstIPs)
+	return totals, dstIPs
+}
+
+// unhealthyShare is the fraction of samples in warning or critical state.
+func (s *synthSamples) unhealthyShare() float64 {
+	total := 0
+	for _, n := range s.health {
+		total += n
+	}
+	if total == 0 {
+		return -1
+	}
+	return float64(s.health["warning"]+s.health["critical"]+s.health["failing"]) / float64(total)
+}
+
+// formatChange renders the relative change from base to cur.
+func formatChange(cur, base float64) string {
+	switch {
+	case cur < 0 || base < 0:
+		return "—"
+	case base == 0 && cur == 0:
+		return "0%"
+	case base == 0:
+		return "new"
+	}
+	return fmt.Sprintf("%+.0f%%", (cur-base)/base*100)
+}
+
+// flowShares maps each row key to its share of the total.
+func flowShares(entries []map[string]interface{}) (map[string]float64, map[string]float64) {
+	values := make(map[string]float64)
+	total := 0.0
+	for _, e := range entries {
+		v, _ := e["avg_bits_per_sec"].(float64)
+		values[fmt.Sprintf("%v", e["key"])] += v
+		total += v
+	}
+	shares := make(map[string]float64)
+	for k, v := range values {
+		if total > 0 {
+			shares[k] = v / total
+		}
+	}
+	return values, shares
+}
+
+// keyASN returns the last number in a flow row key, which is the ASN for
+// destination ASN keys ("Cloudflare (13335)") and the origin for AS paths.
+func keyASN(key string) int {
+	fields := strings.FieldsFunc(key, func(r rune) bool { return r < '0' || r > '9' })
+	if len(fields) == 0 {
+		return 0
+	}
+	asn, _ := strconv.Atoi(fields[len(fields)-1])
+	return asn
+}
+
+// targetASN returns the origin ASN carrying most of the traffic toward the
+// target over all AS paths, or else the top destination ASN.
+func targetASN(paths, dstASNs []map[string]interface{}) (int, string) {
+	for _, c := range []struct {
+		entries []map[string]interface{}
+		source  string
+	}{{paths, "origin AS of most traffic"}, {dstASNs, "top destination ASN"}} {
+		byASN := make(map[int]float64)
+		values, _ := flowShares(c.entries)
+		for k, v := range values {
+			if asn := keyASN(k); asn != 0 {
+				byASN[asn] += v
+			}
+		}
+		best, bestVal := 0, 0.0
+		for asn, v := range byASN {
+			if v > bestVal || (v == bestVal && asn < best) {
+				best, bestVal = asn, v
+			}
+		}
+		if best != 0 {
+			return best, c.source
+		}
+	}
+	return 0, ""
+}
+
+func registerSyntheticFlowTools(s *server.MCPServer, client *kentik.Client, opts Options) {
+	correlate := mcp.NewTool("kentik_synthetic_flow_correlation",
+		mcp.WithDescription("Correlate a synthetic test with flow data toward its target. Derives the target IPs (test settings, measured destination IPs, DNS) and compares, between the window and a baseline, the synthetic metrics (latency, jitter, loss, unhealthy samples) with the traffic toward the target: volume, destination ASN, connectivity type, egress interface and AS path. Use it to check whether a degradation coincides with a traffic shift."),
+		readOnlyTool(),
+		mcp.WithString("test_id",
+			mcp.Required(),
+			mcp.Description("The ID of the synthetic test"),
+		),
+		withWindowParams(opts),
+		withBaselineParams("the synthetic metrics and flows (default: '1d')"),
+		mcp.WithString("targets",
+			mcp.Description("Destination IPs or CIDRs to query flows for (comma-separated), instead of deriving them from the test"),
+		),
+		withDeviceSelector(),
+		mcp.WithNumber("topx",
+			mcp.Description("Rows per flow breakdown. Default: 8"),
+		),
+	)
+	s.AddTool(correlate, makeSyntheticFlowCorrelationHandler(client))
+}
+
+func makeSyntheticFlowCorrelationHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		testID, err := request.RequireString("test_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		window, err := resolveWindow(ctx, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		baseline, ok, err := baselineWindow(request, window)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !ok {
+			baseline = timeWindow{Start: window.Start.AddDate(0, 0, -1), End: window.End.AddDate(0, 0, -1)}
+		}
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
+		topx := 8
+		if v, err := request.RequireFloat("topx"); err == nil && v > 0 {
+			topx = int(v)
+		}
+
+		testData, err := client.V6(ctx, "GET", fmt.Sprintf("/synthetics/v202309/tests/%s", testID), nil)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic test: %v", err)), nil
+		}
+		name, testType, hosts, ips, err := syntheticTestTargets(testData)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic test: %v", err)), nil
+		}
+
+		results := func(w timeWindow) (json.RawMessage, error) {
+			body := map[string]interface{}{
+				"testIds":   []string{testID},
+				"startTime": rfc3339(w.Start),
+				"endTime":   rfc3339(w.End),
+			}
+			return client.V6(ctx, "POST", "/synthetics/v202309/results", body)
+		}
+		curData, err := results(window)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic results: %v", err)), nil
+		}
+		baseData, err := results(baseline)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get baseline synthetic results: %v", err)), nil
+		}
+		curTotals, measured := syntheticTaskTotals(curData)
+		baseTotals, _ := syntheticTaskTotals(baseData)
+
+		// Targets: explicit, else settings plus measured IPs, else DNS
+		source := "test settings and measured destination IPs"
+		targets := ips
+		if v, _ := request.RequireString("targets"); v != "" {
+			targets, source = splitCSV(v), "targets parameter"
+		} else {
+			for _, ip := range measured {
+				if !slices.Contains(targets, ip) {
+					targets = append(targets, ip)
+				}
+			}
+			if len(targets) == 0 {
+				for _, h := range hosts {
+					addrs, err := net.DefaultResolver.LookupIPAddr(ctx, h)
+					if err != nil {
+						continue
+					}
+					for _, a := range addrs {
+						targets = append(targets, a.IP.String())
+					}
+				}
+				source = "local DNS lookup of " + strings.Join(hosts, ", ")
+			}
+		}
+		if len(targets) == 0 {
+			return mcp.NewToolResultError("Could not derive target IPs from the test settings, results or DNS; pass targets explicitly."), nil
+		}
+		truncated := len(targets) > maxCorrelationTargets
+		if truncated {
+			targets = targets[:maxCorrelationTargets]
+		}
+
+		filtersObj := buildFilters(withArguments(request, map[string]string{"dst_ip": strings.Join(targets, ",")}))
+		mkQuery := func(w timeWindow, dims []string, n int) map[string]interface{} {
+			q := map[string]interface{}{
+				"metric":           "bytes",
+				"dimension":        dims,
+				"topx":             n,
+				"depth":            max(n*2, 25),
+				"fastData":         "Auto",
+				"outsort":          "avg_bits_per_sec",
+				"lookback_seconds": 0,
+				"starting_time":    w.Start.Format("2006-01-02 15:04:00"),
+				"ending_time":      w.End.Format("2006-01-02 15:04:00"),
+				"time_format":      "UTC",
+				"hostname_lookup":  true,
+				"all_selected":     true,
+				"filters_obj":      filtersObj,
+			}
+			if len(resolution.Devices) > 0 {
+				resolution.apply(q)
+			} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
+				q["device_name"] = dn
+				q["all_selected"] = false
+			}
+			return q
+		}
+		var queries []subQuery
+		for _, b := range flowBreakdowns {
+			n := topx
+			if b.label == "Volume" {
+				n = 1
+			}
+			queries = append(queries,
+				subQuery{b.label + " (current)", mkQuery(window, b.dims, n)},
+				subQuery{b.label + " (baseline)", mkQuery(baseline, b.dims, n)})
+		}
+		report := runQueries(ctx, client, queries)
+		if report.failed() == len(report.Results) {
+			return mcp.NewToolResultError(fmt.Sprintf("All flow queries failed, e.g.: %v", report.Results[0].Err)), nil
+		}
+
+		// Target ASN: from the current window, else the baseline
+		asnIdx, pathIdx := 2*breakdownIndex("Destination ASNs"), 2*breakdownIndex("AS Paths")
+		var target int
+		var targetSource string
+		for _, off := range []int{0, 1} {
+			if target, targetSource = targetASN(topXEntries(report.Results[pathIdx+off].Data), topXEntries(report.Results[asnIdx+off].Data)); target != 0 {
+				break
+			}
+		}
+
+		var sb strings.Builder
+		sb.WriteString(resolution.note())
+		sb.WriteString(fmt.Sprintf("## Synthetic ↔ Flow Correlation: %s (%s test %s)\n\n", name, testType, testID))
+		sb.WriteString(fmt.Sprintf("- Window: %s\n- Baseline: %s\n", window, baseline))
+		sb.WriteString(fmt.Sprintf("- Target IPs (%s): %s", source, strings.Join(targets, ", ")))
+		if truncated {
+			sb.WriteString(fmt.Sprintf(" (first %d)", maxCorrelationTargets))
+		}
+		if target != 0 {
+			sb.WriteString(fmt.Sprintf("\n- Target ASN: AS%d (%s)", target, targetSource))
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(report.status())
+
+		var findings []string
+		synthDegraded := false
+
+		// Synthetic metrics
+		sb.WriteString("### Synthetic Metrics\n\n")
+		sb.WriteString(fmt.Sprintf("| %-24s | %12s | %12s | %8s |\n", "Metric", "Baseline", "Current", "Change"))
+		sb.WriteString("|" + strings.Repeat("-", 26) + "|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 10) + "|\n")
+		rows := 0
+		for _, kind := range []string{"ping", "http", "dns"} {
+			cur, base := curTotals[kind], baseTotals[kind]
+			if cur == nil && base == nil {
+				continue
+			}
+			if cur == nil {
+				cur = &synthSamples{}
+			}
+			if base == nil {
+				base = &synthSamples{}
+			}
+			row := func(metric, b, c, change string) {
+				sb.WriteString(fmt.Sprintf("| %-24s | %12s | %12s | %8s |\n", kind+" "+metric, b, c, change))
+				rows++
+			}
+			cl, bl := mean(cur.latency), mean(base.latency)
+			row("avg latency", formatMicros(bl), formatMicros(cl), formatChange(cl, bl))
+			cp, bp := percentile(cur.latency, 95), percentile(base.latency, 95)
+			row("p95 latency", formatMicros(bp), formatMicros(cp), formatChange(cp, bp))
+			cj, bj := mean(cur.jitter), mean(base.jitter)
+			row("avg jitter", formatMicros(bj), formatMicros(cj), formatChange(cj, bj))
+			row("loss", formatLoss(base.loss), formatLoss(cur.loss), "")
+			cu, bu := cur.unhealthyShare(), base.unhealthyShare()
+			share := func(v float64) string {
+				if v < 0 {
+					return "—"
+				}
+				return fmt.Sprintf("%.0f%%", v*100)
+			}
+			row("unhealthy samples", share(bu), share(cu), "")
+
+			if cl > 0 && bl > 0 && cl-bl >= 5000 && cl >= bl*1.2 {
+				synthDegraded = true
+				findings = append(findings, fmt.Sprintf("%s latency rose from %s to %s (%s)", kind, formatMicros(bl), formatMicros(cl), formatChange(cl, bl)))
+			}
+			if lc, lb := mean(cur.loss), max(mean(base.loss), 0); lc >= 0 && lc-lb >= 0.01 {
+				synthDegraded = true
+				findings = append(findings, fmt.Sprintf("%s loss rose from %.1f%% to %.1f%%", kind, lb*100, lc*100))
+			}
+			if cu >= 0 && cu-max(bu, 0) >= 0.1 {
+				synthDegraded = true
+				findings = append(findings, fmt.Sprintf("%s unhealthy samples rose from %s to %s", kind, share(bu), share(cu)))
+			}
+		}
+		if rows == 0 {
+			sb.WriteString("| No synthetic results in either window |  |  |  |\n")
+		}
+
+		// Flow volume
+		volume := func(idx int, key string) float64 {
+			total := 0.0
+			for _, e := range topXEntries(report.Results[idx].Data) {
+				v, _ := e[key].(float64)
+				total += v
+			}
+			return total
+		}
+		// A failed query renders as failed rather than as no traffic
+		volumeOK := report.Results[0].Err == nil && report.Results[1].Err == nil
+		rate := func(idx int, v float64) string {
+			if report.Results[idx].Err != nil {
+				return "failed"
+			}
+			return formatBitsPerSec(v)
+		}
+		change := func(cur, base float64) string {
+			if !volumeOK {
+				return "—"
+			}
+			return formatChange(cur, base)
+		}
+		curAvg, baseAvg := volume(0, "avg_bits_per_sec"), volume(1, "avg_bits_per_sec")
+		curMax, baseMax := volume(0, "max_bits_per_sec"), volume(1, "max_bits_per_sec")
+		sb.WriteString("\n### Traffic to Target\n\n")
+		sb.WriteString(fmt.Sprintf("| %-24s | %12s | %12s | %8s |\n", "Metric", "Baseline", "Current", "Change"))
+		sb.WriteString("|" + strings.Repeat("-", 26) + "|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 14) + "|" + strings.Repeat("-", 10) + "|\n")
+		sb.WriteString(fmt.Sprintf("| %-24s | %12s | %12s | %8s |\n", "avg", rate(1, baseAvg), rate(0, curAvg), change(curAvg, baseAvg)))
+		sb.WriteString(fmt.Sprintf("| %-24s | %12s | %12s | %8s |\n", "max", rate(1, baseMax), rate(0, curMax), change(curMax, baseMax)))
+		flowShift := false
+		if volumeOK && baseAvg > 0 && (curAvg >= baseAvg*1.5 || curAvg <= baseAvg/1.5) {
+			flowShift = true
+			findings = append(findings, fmt.Sprintf("traffic to the target changed from %s to %s (%s)", formatBitsPerSec(baseAvg), formatBitsPerSec(curAvg), formatChange(curAvg, baseAvg)))
+		}
+
+		// Breakdowns: shares per window
+		for i := 1; i < len(flowBreakdowns); i++ {
+			label := flowBreakdowns[i].label
+			cur, base := report.Results[2*i], report.Results[2*i+1]
+			sb.WriteString(fmt.Sprintf("\n### %s\n\n", label))
+			if cur.Err != nil || base.Err != nil {
+				sb.WriteString("Query failed; see above.\n")
+				continue
+			}
+			curVals, curShares := flowShares(topXEntries(cur.Data))
+			_, baseShares := flowShares(topXEntries(base.Data))
+			if len(curShares) == 0 && len(baseShares) == 0 {
+				sb.WriteString("No data.\n")
+				continue
+			}
+			var keys []string
+			for k := range curShares {
+				keys = append(keys, k)
+			}
+			for k := range baseShares {
+				if _, ok := curShares[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Slice(keys, func(a, b int) bool {
+				if curShares[keys[a]] != curShares[keys[b]] {
+					return curShares[keys[a]] > curShares[keys[b]]
+				}
+				return baseShares[keys[a]] > baseShares[keys[b]]
+			})
+			sb.WriteString(fmt.Sprintf("| %-45s | %8s | %8s | %8s | %14s |\n", "Key", "Baseline", "Current", "Δ pts", "Current bps"))
+			sb.WriteString("|" + strings.Repeat("-", 47) + "|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 16) + "|\n")
+			for _, k := range keys {
+				c, b := curShares[k], baseShares[k]
+				key := truncateLabel(k, 45)
+				if 2*i == asnIdx && target != 0 && keyASN(k) == target {
+					key = truncateLabel(k, 36) + " (target)"
+				}
+				sb.WriteString(fmt.Sprintf("| %-45s | %7.1f%% | %7.1f%% | %+8.1f | %14s |\n",
+					key, b*100, c*100, (c-b)*100, formatBitsPerSec(curVals[k])))
+				switch {
+				case b == 0 && c >= 0.1 && len(baseShares) > 0:
+					flowShift = true
+					findings = append(findings, fmt.Sprintf("%s: %s is new and carries %.0f%%", label, k, c*100))
+				case c == 0 && b >= 0.1 && len(curShares) > 0:
+					flowShift = true
+					findings = append(findings, fmt.Sprintf("%s: %s is gone (was %.0f%%)", label, k, b*100))
+				case c-b >= 0.2 || b-c >= 0.2:
+					flowShift = true
+					findings = append(findings, fmt.Sprintf("%s: %s went from %.0f%% to %.0f%%", label, k, b*100, c*100))
+				}
+			}
+		}
+
+		sb.WriteString("\n### Findings\n\n")
+		if len(findings) == 0 {
+			sb.WriteString("No significant changes between the windows.\n")
+		}
+		for _, f := range findings {
+			sb.WriteString("- " + f + "\n")
+		}
+		switch {
+		case synthDegraded && flowShift:
+			sb.WriteString("\nThe synthetic degradation coincides with a change in how traffic reaches the target; the shift is a likely cause.\n")
+		case synthDegraded:
+			sb.WriteString("\nThe synthetic metrics degraded while traffic toward the target kept its path; the cause is more likely beyond the network edge or at the target.\n")
+		}
+		sb.WriteString("\n*Flow data covers only traffic seen by the selected devices; it reflects the test's path only where the agents share it.*\n")
+		return mcp.NewToolResultText(sb.String()), nil
+	}
+}
@@ -0,0 +1,26 @@
+package tools
+
+import "testing"
+
+func TestTargetASN(t *testing.T) {
+	row := func(key string, bps float64) map[string]interface{} {
+		return map[string]interface{}{"key": key, "avg_bits_per_sec": bps}
+	}
+	tests := []struct {
+		name           string
+		paths, dstASNs []map[string]interface{}
+		want           int
+		source         string
+	}{
+		{"main path origin", []map[string]interface{}{row("3356 13335", 10), row("1299 174 15169", 30), row("174 15169", 5)}, nil, 15169, "origin AS of most traffic"},
+		{"summed over paths", []map[string]interface{}{row("1 13335", 10), row("2 13335", 10), row("3 15169", 15)}, nil, 13335, "origin AS of most traffic"},
+		{"destination ASN fallback", nil, []map[string]interface{}{row("Cloudflare (13335)", 10), row("Google (15169)", 3)}, 13335, "top destination ASN"},
+		{"no numeric keys", []map[string]interface{}{row("Other", 10)}, nil, 0, ""},
+	}
+	for _, tt := range tests {
+		got, source := targetASN(tt.paths, tt.dstASNs)
+		if got != tt.want || source != tt.source {
+			t.Errorf("%s: targetASN = %d (%s), want %d (%s)", tt.name, got, source, tt.want, tt.source)
+		}
+	}
+}
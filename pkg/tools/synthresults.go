@@ -0,0 +1,370 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+)
+
+// synthValue is a number the synthetics API may encode as a JSON string
+// (int64 fields in the gRPC gateway).
+type synthValue float64
+
+func (v *synthValue) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	*v = synthValue(f)
+	return nil
+}
+
+type synthMetric struct {
+	Current synthValue `json:"current"`
+	Health  string     `json:"health"`
+}
+
+// synthTaskResult is the per-task payload; latency and jitter are in
+// microseconds, packet loss is a fraction.
+type synthTaskResult struct {
+	Target     string       `json:"target"`
+	DstIP      string       `json:"dstIp"`
+	Latency    *synthMetric `json:"latency"`
+	Jitter     *synthMetric `json:"jitter"`
+	PacketLoss *synthMetric `json:"packetLoss"`
+}
+
+type synthResults struct {
+	Results []struct {
+		TestID string `json:"testId"`
+		Time   string `json:"time"`
+		Health string `json:"health"`
+		Agents []struct {
+			AgentID string `json:"agentId"`
+			Health  string `json:"health"`
+			Tasks   []struct {
+				Health string           `json:"health"`
+				Ping   *synthTaskResult `json:"ping"`
+				HTTP   *synthTaskResult `json:"http"`
+				DNS    *synthTaskResult `json:"dns"`
+			} `json:"tasks"`
+		} `json:"agents"`
+	} `json:"results"`
+}
+
+// synthSamples collects the measurements of one agent/target pair, or of
+// a whole test or agent.
+type synthSamples struct {
+	latency, jitter, loss []float64
+	health                map[string]int
+}
+
+func (s *synthSamples) add(r *synthTaskResult, health string) {
+	if s.health == nil {
+		s.health = make(map[string]int)
+	}
+	s.health[healthName(health)]++
+	if r.Latency != nil && r.Latency.Current > 0 {
+		s.latency = append(s.latency, float64(r.Latency.Current))
+	}
+	if r.Jitter != nil {
+		s.jitter = append(s.jitter, float64(r.Jitter.Current))
+	}
+	if r.PacketLoss != nil {
+		s.loss = append(s.loss, float64(r.PacketLoss.Current))
+	}
+}
+
+func (s *synthSamples) merge(o *synthSamples) {
+	if s.health == nil {
+		s.health = make(map[string]int)
+	}
+	s.latency = append(s.latency, o.latency...)
+	s.jitter = append(s.jitter, o.jitter...)
+	s.loss = append(s.loss, o.loss...)
+	for h, n := range o.health {
+		s.health[h] += n
+	}
+}
+
+// synthPair is one agent testing one target of one test.
+type synthPair struct {
+	testID, agentID, task, target string
+	synthSamples
+	latest, latestTime string
+}
+
+// healthName normalizes "HEALTH_WARNING"-style values to "warning".
+func healthName(h string) string {
+	h = strings.ToLower(strings.TrimPrefix(strings.ToUpper(h), "HEALTH_"))
+	if h == "" {
+		return "unknown"
+	}
+	return h
+}
+
+func healthRank(h string) int {
+	switch healthName(h) {
+	case "critical", "failing":
+		return 3
+	case "warning":
+		return 2
+	case "healthy":
+		return 1
+	}
+	return 0
+}
+
+// summarizeSyntheticResults renders per-test and per-agent health tables,
+// the agent/target pairs currently in warning or critical state, and the
+// worst offenders over the window.
+func summarizeSyntheticResults(data json.RawMessage, testNames, agentNames map[string]string) (string, bool) {
+	var res synthResults
+	if err := json.Unmarshal(data, &res); err != nil {
+		return "", false
+	}
+
+	pairs := make(map[string]*synthPair)
+	var order []string
+	for _, row := range res.Results {
+		for _, a := range row.Agents {
+			for _, t := range a.Tasks {
+				for _, tr := range []struct {
+					kind string
+					r    *synthTaskResult
+				}{{"ping", t.Ping}, {"http", t.HTTP}, {"dns", t.DNS}} {
+					kind, r := tr.kind, tr.r
+					if r == nil {
+						continue
+					}
+					target := r.Target
+					if target == "" {
+						target = r.DstIP
+					}
+					key := row.TestID + "|" + a.AgentID + "|" + kind + "|" + target
+					p := pairs[key]
+					if p == nil {
+						p = &synthPair{testID: row.TestID, agentID: a.AgentID, task: kind, target: target}
+						pairs[key] = p
+						order = append(order, key)
+					}
+					p.add(r, t.Health)
+					if row.Time >= p.latestTime {
+						p.latest, p.latestTime = healthName(t.Health), row.Time
+					}
+				}
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return "No synthetic results in this window.\n", true
+	}
+
+	testName := func(id string) string {
+		if n := testNames[id]; n != "" {
+			return n
+		}
+		return id
+	}
+	agentName := func(id string) string {
+		if n := agentNames[id]; n != "" {
+			return n
+		}
+		return id
+	}
+
+	type group struct {
+		name    string
+		members map[string]bool // agents of a test or tests of an agent
+		synthSamples
+		worst          string
+		pairs, failing int
+	}
+	tests := make(map[string]*group)
+	agents := make(map[string]*group)
+	for _, key := range order {
+		p := pairs[key]
+		for _, g := range []struct {
+			m      map[string]*group
+			id     string
+			name   string
+			member string
+		}{
+			{tests, p.testID, testName(p.testID), p.agentID},
+			{agents, p.agentID, agentName(p.agentID), p.testID},
+		} {
+			e := g.m[g.id]
+			if e == nil {
+				e = &group{name: g.name, members: make(map[string]bool)}
+				g.m[g.id] = e
+			}
+			e.members[g.member] = true
+			e.merge(&p.synthSamples)
+			e.pairs++
+			if healthRank(p.latest) >= 2 {
+				e.failing++
+			}
+			if healthRank(p.latest) > healthRank(e.worst) {
+				e.worst = p.latest
+			}
+		}
+	}
+
+	var sb strings.Builder
+	writeGroups := func(title, nameCol, memberCol string, groups map[string]*group) {
+		ids := make([]string, 0, len(groups))
+		for id := range groups {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			gi, gj := groups[ids[i]], groups[ids[j]]
+			if healthRank(gi.worst) != healthRank(gj.worst) {
+				return healthRank(gi.worst) > healthRank(gj.worst)
+			}
+			return gi.name < gj.name
+		})
+		sb.WriteString(fmt.Sprintf("### %s (%d)\n\n", title, len(ids)))
+		sb.WriteString(fmt.Sprintf("| %-35s | %-8s | %6s | %10s | %10s | %10s | %7s | %7s |\n",
+			nameCol, "Health", memberCol, "Avg Lat", "P95 Lat", "Jitter", "Loss", "Failing"))
+		sb.WriteString("|" + strings.Repeat("-", 37) + "|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 8) +
+			"|" + strings.Repeat("-", 12) + "|" + strings.Repeat("-", 12) + "|" + strings.Repeat("-", 12) +
+			"|" + strings.Repeat("-", 9) + "|" + strings.Repeat("-", 9) + "|\n")
+		for _, id := range ids {
+			g := groups[id]
+			sb.WriteString(fmt.Sprintf("| %-35s | %-8s | %6d | %10s | %10s | %10s | %7s | %7s |\n",
+				truncateLabel(g.name, 35), g.worst, len(g.members),
+				formatMicros(mean(g.latency)), formatMicros(percentile(g.latency, 95)),
+				formatMicros(mean(g.jitter)), formatLoss(g.loss), fmt.Sprintf("%d/%d", g.failing, g.pairs)))
+		}
+		sb.WriteString("\n")
+	}
+	writeGroups("Tests", "Test", "Agents", tests)
+	writeGroups("Agents", "Agent", "Tests", agents)
+
+	var unhealthy, ranked []*synthPair
+	for _, key := range order {
+		p := pairs[key]
+		if healthRank(p.latest) >= 2 {
+			unhealthy = append(unhealthy, p)
+		}
+		ranked = append(ranked, p)
+	}
+
+	sb.WriteString(fmt.Sprintf("### Warning / critical now (%d)\n\n", len(unhealthy)))
+	if len(unhealthy) == 0 {
+		sb.WriteString("All agent/target pairs are healthy in the latest interval.\n\n")
+	}
+	sort.Slice(unhealthy, func(i, j int) bool { return healthRank(unhealthy[i].latest) > healthRank(unhealthy[j].latest) })
+	for _, p := range unhealthy {
+		sb.WriteString(fmt.Sprintf("- **%s** %s → %s (%s, %s): avg latency %s, loss %s\n",
+			p.latest, agentName(p.agentID), p.target, testName(p.testID), p.task,
+			formatMicros(mean(p.latency)), formatLoss(p.loss)))
+	}
+	if len(unhealthy) > 0 {
+		sb.WriteString("\n")
+	}
+
+	score := func(p *synthPair) float64 {
+		return float64(2*p.health["critical"]+p.health["warning"])*1000 + max(mean(p.loss), 0)*100
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := score(ranked[i]), score(ranked[j])
+		if si != sj {
+			return si > sj
+		}
+		return percentile(ranked[i].latency, 95) > percentile(ranked[j].latency, 95)
+	})
+	ranked = ranked[:min(10, len(ranked))]
+	sb.WriteString("### Worst offenders\n\n")
+	sb.WriteString(fmt.Sprintf("| %4s | %-25s | %-25s | %-30s | %4s | %4s | %10s | %7s |\n",
+		"Rank", "Test", "Agent", "Target", "Crit", "Warn", "P95 Lat", "Loss"))
+	sb.WriteString("|" + strings.Repeat("-", 6) + "|" + strings.Repeat("-", 27) + "|" + strings.Repeat("-", 27) +
+		"|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 6) + "|" + strings.Repeat("-", 6) +
+		"|" + strings.Repeat("-", 12) + "|" + strings.Repeat("-", 9) + "|\n")
+	for i, p := range ranked {
+		sb.WriteString(fmt.Sprintf("| %4d | %-25s | %-25s | %-30s | %4d | %4d | %10s | %7s |\n",
+			i+1, truncateLabel(testName(p.testID), 25), truncateLabel(agentName(p.agentID), 25),
+			truncateLabel(p.task+" "+p.target, 30), p.health["critical"], p.health["warning"],
+			formatMicros(percentile(p.latency, 95)), formatLoss(p.loss)))
+	}
+	return sb.String(), true
+}
+
+// syntheticNames maps test and agent IDs to names. Lookup failures leave
+// the maps empty; the summary then shows IDs.
+func syntheticNames(ctx context.Context, client *kentik.Client) (tests, agents map[string]string) {
+	tests, agents = make(map[string]string), make(map[string]string)
+	var tr struct {
+		Tests []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"tests"`
+	}
+	if data, err := client.V6(ctx, "GET", "/synthetics/v202309/tests", nil); err == nil && json.Unmarshal(data, &tr) == nil {
+		for _, t := range tr.Tests {
+			tests[t.ID] = t.Name
+		}
+	}
+	if all, err := fetchSyntheticAgents(ctx, client); err == nil {
+		for _, a := range all {
+			agents[a.ID] = a.displayName()
+		}
+	}
+	return tests, agents
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return -1
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile returns the nearest-rank percentile, or -1 without values.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return -1
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	idx := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[max(0, min(idx, len(sorted)-1))]
+}
+
+// formatMicros renders a microsecond value as milliseconds.
+func formatMicros(us float64) string {
+	if us < 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.1f ms", us/1000)
+}
+
+// formatLoss renders the mean of packet loss fractions as a percentage.
+func formatLoss(loss []float64) string {
+	if len(loss) == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.1f%%", mean(loss)*100)
+}
@@ -0,0 +1,452 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxSLAChunks bounds the results requests of one report.
+const maxSLAChunks = 120
+
+// slaPolicy decides whether an agent's measurement breaches the SLO.
+// Without thresholds a critical health state is a breach.
+type slaPolicy struct {
+	latencyUs float64 // 0: not checked
+	loss      float64 // fraction, 0: not checked
+	quorum    float64 // share of agents that must breach for an interval to count
+}
+
+func (p slaPolicy) thresholds() bool {
+	return p.latencyUs > 0 || p.loss > 0
+}
+
+func (p slaPolicy) breached(r *synthTaskResult, health string) bool {
+	if !p.thresholds() {
+		return healthRank(health) >= 3
+	}
+	if p.latencyUs > 0 && r.Latency != nil && float64(r.Latency.Current) > p.latencyUs {
+		return true
+	}
+	return p.loss > 0 && r.PacketLoss != nil && float64(r.PacketLoss.Current) > p.loss
+}
+
+func (p slaPolicy) String() string {
+	var parts []string
+	if p.latencyUs > 0 {
+		parts = append(parts, fmt.Sprintf("latency > %s", formatMicros(p.latencyUs)))
+	}
+	if p.loss > 0 {
+		parts = append(parts, fmt.Sprintf("loss > %.1f%%", p.loss*100))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "health critical")
+	}
+	return fmt.Sprintf("an agent breaches when %s; an interval breaches when at least %.0f%% of its agents breach",
+		strings.Join(parts, " or "), p.quorum*100)
+}
+
+// slaInterval is one test run across its agents.
+type slaInterval struct {
+	agents, breaching int
+}
+
+// slaTest accumulates one test's results across chunks.
+type slaTest struct {
+	id, name  string
+	period    time.Duration
+	intervals map[string]*slaInterval
+	latency   map[string][]float64 // per task type
+	loss      []float64
+}
+
+func (t *slaTest) primaryLatency() []float64 {
+	for _, kind := range []string{"http", "ping", "dns"} {
+		if len(t.latency[kind]) > 0 {
+			return t.latency[kind]
+		}
+	}
+	return nil
+}
+
+// slaIncident is a run of consecutive breached intervals.
+type slaIncident struct {
+	start, end time.Time
+}
+
+// slaSummary is the computed report row of a test.
+type slaSummary struct {
+	*slaTest
+	observed, breached int
+	expected           float64
+	incidents          []slaIncident
+}
+
+func (s slaSummary) availability() float64 {
+	if s.observed == 0 {
+		return -1
+	}
+	return 100 * float64(s.observed-s.breached) / float64(s.observed)
+}
+
+func (s slaSummary) breachMinutes() float64 {
+	return float64(s.breached) * s.period.Minutes()
+}
+
+func (s slaSummary) coverage() float64 {
+	if s.expected <= 0 {
+		return -1
+	}
+	return min(100*float64(s.observed)/s.expected, 100)
+}
+
+// add folds one results response into the tests.
+func (p slaPolicy) add(tests map[string]*slaTest, data json.RawMessage) error {
+	var res synthResults
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("parse results: %w", err)
+	}
+	for _, row := range res.Results {
+		t := tests[row.TestID]
+		if t == nil {
+			continue
+		}
+		iv := t.intervals[row.Time]
+		if iv == nil {
+			// Chunks share their boundary, so a run may be returned twice
+			iv = &slaInterval{}
+			t.intervals[row.Time] = iv
+		} else if iv.agents > 0 {
+			continue
+		}
+		for _, a := range row.Agents {
+			breach := false
+			for _, task := range a.Tasks {
+				for kind, r := range map[string]*synthTaskResult{"ping": task.Ping, "http": task.HTTP, "dns": task.DNS} {
+					if r == nil {
+						continue
+					}
+					if r.Latency != nil && r.Latency.Current > 0 {
+						t.latency[kind] = append(t.latency[kind], float64(r.Latency.Current))
+					}
+					if r.PacketLoss != nil {
+						t.loss = append(t.loss, float64(r.PacketLoss.Current))
+					}
+					breach = breach || p.breached(r, task.Health)
+				}
+			}
+			iv.agents++
+			if breach {
+				iv.breaching++
+			}
+		}
+	}
+	return nil
+}
+
+// summarize counts breached intervals and merges them into incidents.
+func (p slaPolicy) summarize(t *slaTest, window timeWindow) slaSummary {
+	s := slaSummary{slaTest: t, expected: window.End.Sub(window.Start).Seconds() / t.period.Seconds()}
+	var times []time.Time
+	breached := make(map[time.Time]bool)
+	for ts, iv := range t.intervals {
+		at, err := time.Parse(time.RFC3339, ts)
+		if err != nil || iv.agents == 0 {
+			continue
+		}
+		times = append(times, at)
+		if float64(iv.breaching) >= p.quorum*float64(iv.agents) {
+			breached[at] = true
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	s.observed = len(times)
+	for i, at := range times {
+		if !breached[at] {
+			continue
+		}
+		s.breached++
+		end := at.Add(t.period)
+		// Extend the open incident when the previous run breached too and
+		// no run is missing in between; a data gap ends the incident
+		if n := len(s.incidents); n > 0 && i > 0 && breached[times[i-1]] && at.Sub(times[i-1]) <= t.period*3/2 {
+			s.incidents[n-1].end = end
+			continue
+		}
+		s.incidents = append(s.incidents, slaIncident{start: at, end: end})
+	}
+	return s
+}
+
+// fetchSyntheticTests returns the tests by ID with their name and period.
+func fetchSyntheticTests(ctx context.Context, client *kentik.Client) (map[string]*slaTest, error) {
+	data, err := client.V6(ctx, "GET", "/synthetics/v202309/tests", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Tests []struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			Settings struct {
+				Period synthValue `json:"period"`
+			} `json:"settings"`
+		} `json:"tests"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse tests: %w", err)
+	}
+	tests := make(map[string]*slaTest)
+	for _, t := range resp.Tests {
+		period := time.Duration(t.Settings.Period) * time.Second
+		if period <= 0 {
+			period = time.Minute
+		}
+		tests[t.ID] = &slaTest{id: t.ID, name: t.Name, period: period,
+			intervals: make(map[string]*slaInterval), latency: make(map[string][]float64)}
+	}
+	return tests, nil
+}
+
+func registerSyntheticSLATools(s *server.MCPServer, client *kentik.Client, opts Options) {
+	report := mcp.NewTool("kentik_synthetic_sla_report",
+		mcp.WithDescription("Availability/SLA report for synthetic tests over days or weeks (default: the last 7 days): per test availability %, breach minutes, longest breaches, data coverage and latency percentiles, checked against SLO thresholds. Results are fetched in chunks. Output as markdown or CSV for sharing."),
+		readOnlyTool(),
+		mcp.WithString("test_ids",
+			mcp.Required(),
+			mcp.Description("Comma-separated list of synthetic test IDs"),
+		),
+		withWindowParams(opts),
+		mcp.WithNumber("latency_slo_ms",
+			mcp.Description("An agent measurement breaches above this latency"),
+		),
+		mcp.WithNumber("loss_slo_pct",
+			mcp.Description("An agent measurement breaches above this packet loss (percent). Without latency_slo_ms and loss_slo_pct, a critical health state is a breach."),
+		),
+		mcp.WithNumber("agent_quorum_pct",
+			mcp.Description("An interval breaches when at least this share of its agents breach. Default: 50"),
+		),
+		mcp.WithNumber("availability_target_pct",
+			mcp.Description("Availability objective per test. Default: 99.9"),
+		),
+		mcp.WithNumber("chunk_hours",
+			mcp.Description("Hours of results per request. Default: 24"),
+		),
+		mcp.WithString("format",
+			mcp.Description("'markdown' (default) or 'csv'"),
+		),
+	)
+	s.AddTool(report, makeSyntheticSLAReportHandler(client))
+}
+
+func makeSyntheticSLAReportHandler(client *kentik.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		testIDsStr, err := request.RequireString("test_ids")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		testIDs := splitCSV(testIDsStr)
+		if len(testIDs) == 0 {
+			return mcp.NewToolResultError("test_ids must list at least one test"), nil
+		}
+		args := request.GetArguments()
+		if args["start_time"] == nil && args["lookback"] == nil && args["lookback_seconds"] == nil {
+			request = withArguments(request, map[string]string{"lookback": "7d"})
+		}
+		window, err := resolveWindow(ctx, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		policy := slaPolicy{quorum: 0.5}
+		if v, err := request.RequireFloat("latency_slo_ms"); err == nil && v > 0 {
+			policy.latencyUs = v * 1000
+		}
+		if v, err := request.RequireFloat("loss_slo_pct"); err == nil && v > 0 {
+			policy.loss = v / 100
+		}
+		if v, err := request.RequireFloat("agent_quorum_pct"); err == nil && v > 0 {
+			policy.quorum = min(v, 100) / 100
+		}
+		target := 99.9
+		if v, err := request.RequireFloat("availability_target_pct"); err == nil && v > 0 {
+			target = v
+		}
+		chunk := 24 * time.Hour
+		if v, err := request.RequireFloat("chunk_hours"); err == nil && v > 0 {
+			chunk = time.Duration(v * float64(time.Hour))
+		}
+		if n := window.End.Sub(window.Start) / chunk; n >= maxSLAChunks {
+			return mcp.NewToolResultError(fmt.Sprintf("The window needs %d requests of %s; raise chunk_hours or shorten the window (max %d).",
+				n+1, formatDuration(chunk), maxSLAChunks)), nil
+		}
+
+		all, err := fetchSyntheticTests(ctx, client)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list synthetic tests: %v", err)), nil
+		}
+		tests := make(map[string]*slaTest)
+		for _, id := range testIDs {
+			if all[id] == nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Synthetic test %s not found", id)), nil
+			}
+			tests[id] = all[id]
+		}
+
+		// Chunks run one after another to stay within the API rate limits
+		var failed []string
+		chunks := 0
+		for start := window.Start; start.Before(window.End); start = start.Add(chunk) {
+			end := start.Add(chunk)
+			if end.After(window.End) {
+				end = window.End
+			}
+			chunks++
+			body := map[string]interface{}{
+				"testIds":   testIDs,
+				"startTime": rfc3339(start),
+				"endTime":   rfc3339(end),
+			}
+			data, err := client.V6(ctx, "POST", "/synthetics/v202309/results", body)
+			if err == nil {
+				err = policy.add(tests, data)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic results: %v", err)), nil
+				}
+				failed = append(failed, fmt.Sprintf("%s: %v", timeWindow{Start: start, End: end}, err))
+			}
+		}
+		if len(failed) == chunks {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get synthetic results: %s", failed[0])), nil
+		}
+
+		summaries := make([]slaSummary, 0, len(testIDs))
+		for _, id := range testIDs {
+			summaries = append(summaries, policy.summarize(tests[id], window))
+		}
+
+		if format, _ := request.RequireString("format"); strings.EqualFold(format, "csv") {
+			return mcp.NewToolResultText(formatSLACSV(summaries, window, target)), nil
+		}
+		return mcp.NewToolResultText(formatSLAMarkdown(summaries, window, policy, target, chunks, failed)), nil
+	}
+}
+
+func formatSLAMarkdown(summaries []slaSummary, window timeWindow, policy slaPolicy, target float64, chunks int, failed []string) string {
+	var sb strings.Builder
+	sb.WriteString("## Synthetic SLA Report\n\n")
+	sb.WriteString(fmt.Sprintf("- Window: %s\n", window))
+	sb.WriteString(fmt.Sprintf("- Objective: %g%% availability; %s\n", target, policy))
+	sb.WriteString(fmt.Sprintf("- Results fetched in %d chunks", chunks))
+	if len(failed) > 0 {
+		sb.WriteString(fmt.Sprintf(", %d failed (their intervals count as missing data)", len(failed)))
+	}
+	sb.WriteString("\n\n")
+
+	sb.WriteString(fmt.Sprintf("| %-30s | %9s | %3s | %9s | %8s | %9s | %9s | %9s | %6s |\n",
+		"Test", "Avail.", "Met", "Breach", "Coverage", "P50 Lat", "P95 Lat", "P99 Lat", "Loss"))
+	sb.WriteString("|" + strings.Repeat("-", 32) + "|" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", 5) +
+		"|" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", 10) + "|" + strings.Repeat("-", 11) +
+		"|" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", 11) + "|" + strings.Repeat("-", 8) + "|\n")
+	for _, s := range summaries {
+		avail, met := "—", "—"
+		if a := s.availability(); a >= 0 {
+			avail = fmt.Sprintf("%.3f%%", a)
+			met = "✓"
+			if a < target {
+				met = "✗"
+			}
+		}
+		coverage := "—"
+		if c := s.coverage(); c >= 0 {
+			coverage = fmt.Sprintf("%.1f%%", c)
+		}
+		lat := s.primaryLatency()
+		sb.WriteString(fmt.Sprintf("| %-30s | %9s | %3s | %9s | %8s | %9s | %9s | %9s | %6s |\n",
+			truncateLabel(fmt.Sprintf("%s (%s)", s.name, s.id), 30), avail, met, formatBreach(s.breachMinutes()), coverage,
+			formatMicros(percentile(lat, 50)), formatMicros(percentile(lat, 95)), formatMicros(percentile(lat, 99)), formatLoss(s.loss)))
+	}
+
+	for _, s := range summaries {
+		if len(s.incidents) == 0 {
+			continue
+		}
+		incidents := append([]slaIncident(nil), s.incidents...)
+		sort.SliceStable(incidents, func(i, j int) bool {
+			return incidents[i].end.Sub(incidents[i].start) > incidents[j].end.Sub(incidents[j].start)
+		})
+		sb.WriteString(fmt.Sprintf("\n### %s: %d breaches, longest first\n\n", s.name, len(incidents)))
+		for i, inc := range incidents {
+			if i == 10 {
+				sb.WriteString(fmt.Sprintf("- … %d more\n", len(incidents)-10))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- %s → %s UTC (%s)\n", inc.start.Format(time.DateTime), inc.end.Format(time.DateTime),
+				formatDuration(inc.end.Sub(inc.start))))
+		}
+	}
+
+	if len(failed) > 0 {
+		sb.WriteString("\n### Failed Chunks\n\n")
+		for _, f := range failed {
+			sb.WriteString("- " + f + "\n")
+		}
+	}
+	sb.WriteString("\n*Availability counts observed intervals only; coverage shows the share of expected test runs with data.*\n")
+	return sb.String()
+}
+
+func formatSLACSV(summaries []slaSummary, window timeWindow, target float64) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write([]string{"test_id", "test_name", "window_start", "window_end", "availability_pct", "target_pct", "met",
+		"intervals", "breached_intervals", "breach_minutes", "breaches", "coverage_pct", "p50_ms", "p95_ms", "p99_ms", "avg_loss_pct"})
+	num := func(v float64, prec int) string {
+		if v < 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.*f", prec, v)
+	}
+	for _, s := range summaries {
+		a := s.availability()
+		met := ""
+		if a >= 0 {
+			met = fmt.Sprintf("%t", a >= target)
+		}
+		lat := s.primaryLatency()
+		ms := func(p float64) string {
+			v := percentile(lat, p)
+			if v < 0 {
+				return ""
+			}
+			return num(v/1000, 1)
+		}
+		loss := mean(s.loss)
+		if loss >= 0 {
+			loss *= 100
+		}
+		_ = w.Write([]string{s.id, s.name, rfc3339(window.Start), rfc3339(window.End), num(a, 3), num(target, 3), met,
+			fmt.Sprint(s.observed), fmt.Sprint(s.breached), num(s.breachMinutes(), 1), fmt.Sprint(len(s.incidents)),
+			num(s.coverage(), 1), ms(50), ms(95), ms(99), num(loss, 2)})
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// formatBreach renders breach minutes, switching to hours when long.
+func formatBreach(minutes float64) string {
+	if minutes >= 120 {
+		return fmt.Sprintf("%.1f h", minutes/60)
+	}
+	return fmt.Sprintf("%.0f min", minutes)
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLASummarize(t *testing.T) {
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(min int) string { return start.Add(time.Duration(min) * time.Minute).Format(time.RFC3339) }
+	test := &slaTest{id: "1", name: "web", period: time.Minute, intervals: map[string]*slaInterval{
+		at(0): {agents: 4},
+		at(1): {agents: 4, breaching: 2}, // quorum reached: incident 1
+		at(2): {agents: 4, breaching: 3},
+		at(3): {agents: 4, breaching: 1}, // below quorum
+		at(4): {agents: 4, breaching: 4}, // incident 2
+		// 5-7 missing: a failed chunk must not join incidents 2 and 3
+		at(8): {agents: 4, breaching: 4}, // incident 3
+		at(9): {agents: 0},               // no agents: not observed
+	}}
+	window := timeWindow{Start: start, End: start.Add(10 * time.Minute)}
+	s := slaPolicy{quorum: 0.5}.summarize(test, window)
+
+	if s.observed != 6 || s.breached != 4 {
+		t.Errorf("observed, breached = %d, %d; want 6, 4", s.observed, s.breached)
+	}
+	want := []slaIncident{
+		{start.Add(1 * time.Minute), start.Add(3 * time.Minute)},
+		{start.Add(4 * time.Minute), start.Add(5 * time.Minute)},
+		{start.Add(8 * time.Minute), start.Add(9 * time.Minute)},
+	}
+	if len(s.incidents) != len(want) {
+		t.Fatalf("incidents = %v, want %v", s.incidents, want)
+	}
+	for i, inc := range s.incidents {
+		if !inc.start.Equal(want[i].start) || !inc.end.Equal(want[i].end) {
+			t.Errorf("incident %d = %v → %v, want %v → %v", i, inc.start, inc.end, want[i].start, want[i].end)
+		}
+	}
+	if got := s.availability(); got < 33.3 || got > 33.4 {
+		t.Errorf("availability = %.2f, want 33.33", got)
+	}
+	if got := s.coverage(); got != 60 {
+		t.Errorf("coverage = %.1f, want 60", got)
+	}
+	if got := s.breachMinutes(); got != 4 {
+		t.Errorf("breach minutes = %.1f, want 4", got)
+	}
+}
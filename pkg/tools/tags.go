@@ -12,11 +12,13 @@ import (
 func registerTagTools(s *server.MCPServer, client *kentik.Client) {
 	listTags := mcp.NewTool("kentik_list_tags",
 		mcp.WithDescription("List all flow tags in Kentik. Flow tags are used to classify and label network traffic."),
+		readOnlyTool(),
 	)
 	s.AddTool(listTags, makeListTagsHandler(client))
 
 	getTag := mcp.NewTool("kentik_get_tag",
 		mcp.WithDescription("Get information about a specific flow tag by ID."),
+		readOnlyTool(),
 		mcp.WithString("tag_id",
 			mcp.Required(),
 			mcp.Description("The ID of the tag"),
@@ -27,7 +29,7 @@ func registerTagTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeListTagsHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		data, err := client.V5("GET", "/tags", nil)
+		data, err := client.V5(ctx, "GET", "/tags", nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list tags: %v", err)), nil
 		}
@@ -41,7 +43,7 @@ func makeGetTagHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V5("GET", fmt.Sprintf("/tag/%s", tagID), nil)
+		data, err := client.V5(ctx, "GET", fmt.Sprintf("/tag/%s", tagID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get tag: %v", err)), nil
 		}
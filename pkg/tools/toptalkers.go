@@ -10,9 +10,10 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func registerTopTalkersTools(s *server.MCPServer, client *kentik.Client) {
+func registerTopTalkersTools(s *server.MCPServer, client *kentik.Client, opts Options) {
 	topTalkers := mcp.NewTool("kentik_query_toptalkers",
 		mcp.WithDescription("Quick query: find the top talkers (IPs, ASNs, or ports) by traffic volume or flow count. Simplified interface — just specify what you want to rank and the time range. Returns a formatted table with bandwidth and percentage."),
+		readOnlyTool(),
 		mcp.WithString("rank_by",
 			mcp.Required(),
 			mcp.Description("What to rank: 'src_ip', 'dst_ip', 'src_asn', 'dst_asn', 'src_port', 'dst_port', 'protocol', 'src_country', 'dst_country', 'interface'"),
@@ -21,20 +22,12 @@ func registerTopTalkersTools(s *server.MCPServer, client *kentik.Client) {
 			mcp.Description("Measure by: 'volume' (bytes, default) or 'flows' (fps)"),
 		),
 		mcp.WithNumber("lookback_seconds",
-			mcp.Description("Time range in seconds. Default: 3600 (1 hour)"),
+			mcp.Description(fmt.Sprintf("Time range in seconds. Default: %d", int(opts.lookback()))),
 		),
 		mcp.WithNumber("limit",
 			mcp.Description("Number of results. Default: 10"),
 		),
-		mcp.WithString("device_name",
-			mcp.Description("Comma-delimited device names to query."),
-		),
-		mcp.WithString("device_label",
-			mcp.Description("Auto-resolve devices by label."),
-		),
-		mcp.WithString("site_name",
-			mcp.Description("Auto-resolve devices by site."),
-		),
+		withDeviceSelector(),
 		mcp.WithString("dst_connect_type",
 			mcp.Description("Filter by destination connectivity type. E.g. 'free_pni,transit,ix' for external."),
 		),
@@ -47,6 +40,7 @@ func registerTopTalkersTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeTopTalkersHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		opts := optionsFrom(ctx)
 		rankBy, err := request.RequireString("rank_by")
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
@@ -76,7 +70,7 @@ func makeTopTalkersHandler(client *kentik.Client) server.ToolHandlerFunc {
 			metricStr = "fps"
 		}
 
-		lookback := 3600.0
+		lookback := opts.lookback()
 		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
 			lookback = lb
 		}
@@ -85,7 +79,10 @@ func makeTopTalkersHandler(client *kentik.Client) server.ToolHandlerFunc {
 			limit = lm
 		}
 
-		resolvedDevices := resolveDeviceShortcuts(client, request)
+		resolution := resolveDevices(ctx, client, request)
+		if resolution.failed() {
+			return mcp.NewToolResultError(resolution.errorText()), nil
+		}
 
 		outsort := "avg_bits_per_sec"
 		if metricStr == "fps" {
@@ -105,9 +102,8 @@ func makeTopTalkersHandler(client *kentik.Client) server.ToolHandlerFunc {
 			"all_selected":     true,
 		}
 
-		if resolvedDevices != "" {
-			query["device_name"] = resolvedDevices
-			query["all_selected"] = false
+		if len(resolution.Devices) > 0 {
+			resolution.apply(query)
 		} else if dn, err := request.RequireString("device_name"); err == nil && dn != "" {
 			query["device_name"] = dn
 			query["all_selected"] = false
@@ -124,12 +120,12 @@ func makeTopTalkersHandler(client *kentik.Client) server.ToolHandlerFunc {
 			},
 		}
 
-		data, err := client.V5("POST", "/query/topXdata", body)
+		data, err := client.V5(ctx, "POST", "/query/topXdata", body)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Query failed: %v", err)), nil
 		}
 
 		summary := summarizeQueryResults(data, query)
-		return mcp.NewToolResultText(fmt.Sprintf("## Top Talkers by %s (%s)\n\n%s", rankBy, metricStr, summary)), nil
+		return mcp.NewToolResultText(fmt.Sprintf("%s## Top Talkers by %s (%s)\n\n%s", resolution.note(), rankBy, metricStr, summary)), nil
 	}
 }
@@ -0,0 +1,388 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// traceResponse is the v202309 trace payload. Hop latencies are in
+// microseconds.
+type traceResponse struct {
+	Nodes map[string]struct {
+		IP       string     `json:"ip"`
+		ASN      synthValue `json:"asn"`
+		AsName   string     `json:"asName"`
+		DNSName  string     `json:"dnsName"`
+		Location struct {
+			City    string `json:"city"`
+			Country string `json:"country"`
+		} `json:"location"`
+	} `json:"nodes"`
+	Paths []struct {
+		AgentID  string `json:"agentId"`
+		TargetIP string `json:"targetIp"`
+		Traces   []struct {
+			IsComplete bool `json:"isComplete"`
+			Hops       []struct {
+				Latency synthValue `json:"latency"`
+				NodeID  string     `json:"nodeId"`
+			} `json:"hops"`
+		} `json:"traces"`
+	} `json:"paths"`
+}
+
+// traceHop aggregates one hop position over all traces of a path.
+type traceHop struct {
+	ip, dns, asName string
+	asn             int
+	latency         []float64
+	probes          int // traces long enough to reach this position
+	responses       int
+	alternates      int // distinct responding nodes besides the dominant one
+}
+
+func (h traceHop) loss() float64 {
+	if h.probes == 0 {
+		return 0
+	}
+	return 1 - float64(h.responses)/float64(h.probes)
+}
+
+// tracePath is the reconstructed path of one agent to one target.
+type tracePath struct {
+	agentID, target string
+	traces          int
+	complete        int
+	variants        int
+	hops            []traceHop
+}
+
+// key identifies the path across windows.
+func (p *tracePath) key() string {
+	return p.agentID + "|" + p.target
+}
+
+// asPath returns the ASNs along the dominant path, without repeats.
+func (p *tracePath) asPath() []traceHop {
+	var out []traceHop
+	for _, h := range p.hops {
+		if h.asn == 0 || (len(out) > 0 && out[len(out)-1].asn == h.asn) {
+			continue
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// endLatency is the mean latency of the last responding hop, or -1.
+func (p *tracePath) endLatency() float64 {
+	for i := len(p.hops) - 1; i >= 0; i-- {
+		if len(p.hops[i].latency) > 0 {
+			return mean(p.hops[i].latency)
+		}
+	}
+	return -1
+}
+
+// reconstructPaths builds one path per agent and target, taking the most
+// frequent responding node at each hop position.
+func reconstructPaths(data json.RawMessage) ([]*tracePath, bool) {
+	var resp traceResponse
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Paths == nil {
+		return nil, false
+	}
+
+	var paths []*tracePath
+	for _, rp := range resp.Paths {
+		p := &tracePath{agentID: rp.AgentID, target: rp.TargetIP, traces: len(rp.Traces)}
+		variants := make(map[string]bool)
+		var counts []map[string]int
+		var latencies []map[string][]float64
+		var probes, responses []int
+		for _, tr := range rp.Traces {
+			if tr.IsComplete {
+				p.complete++
+			}
+			var sig []string
+			for i, hop := range tr.Hops {
+				if i >= len(counts) {
+					counts = append(counts, make(map[string]int))
+					latencies = append(latencies, make(map[string][]float64))
+					probes = append(probes, 0)
+					responses = append(responses, 0)
+				}
+				probes[i]++
+				node, ok := resp.Nodes[hop.NodeID]
+				if hop.NodeID == "" || !ok || node.IP == "" || hop.Latency <= 0 {
+					sig = append(sig, "*")
+					continue
+				}
+				responses[i]++
+				counts[i][hop.NodeID]++
+				latencies[i][hop.NodeID] = append(latencies[i][hop.NodeID], float64(hop.Latency))
+				sig = append(sig, node.IP)
+			}
+			variants[strings.Join(sig, ">")] = true
+		}
+		p.variants = len(variants)
+
+		for i := range counts {
+			h := traceHop{probes: probes[i], responses: responses[i]}
+			best, bestN := "", 0
+			for id, n := range counts[i] {
+				if n > bestN || (n == bestN && id < best) {
+					best, bestN = id, n
+				}
+			}
+			if best != "" {
+				node := resp.Nodes[best]
+				h.ip, h.dns, h.asName, h.asn = node.IP, node.DNSName, node.AsName, int(node.ASN)
+				h.latency = latencies[i][best]
+				h.alternates = len(counts[i]) - 1
+			}
+			p.hops = append(p.hops, h)
+		}
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].key() < paths[j].key() })
+	return paths, true
+}
+
+// asLabel renders an ASN with its name, e.g. "AS13335 (CLOUDFLARENET)".
+func asLabel(asn int, name string) string {
+	if asn == 0 {
+		return "—"
+	}
+	if name == "" {
+		return fmt.Sprintf("AS%d", asn)
+	}
+	return fmt.Sprintf("AS%d (%s)", asn, name)
+}
+
+// writeTracePath renders a path's AS summary and hop table.
+func writeTracePath(sb *strings.Builder, p *tracePath, agentName string) {
+	sb.WriteString(fmt.Sprintf("### %s → %s\n\n", agentName, p.target))
+	sb.WriteString(fmt.Sprintf("%d traces, %d path variant(s), %d complete\n\n", p.traces, p.variants, p.complete))
+	var as []string
+	for _, h := range p.asPath() {
+		as = append(as, asLabel(h.asn, h.asName))
+	}
+	if len(as) > 0 {
+		sb.WriteString("AS path: " + strings.Join(as, " → ") + "\n\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("| %3s | %-39s | %-35s | %-25s | %10s | %6s |\n", "#", "IP", "rDNS", "ASN", "Avg Lat", "Loss"))
+	sb.WriteString("|" + strings.Repeat("-", 5) + "|" + strings.Repeat("-", 41) + "|" + strings.Repeat("-", 37) +
+		"|" + strings.Repeat("-", 27) + "|" + strings.Repeat("-", 12) + "|" + strings.Repeat("-", 8) + "|\n")
+	for i, h := range p.hops {
+		ip := h.ip
+		if ip == "" {
+			ip = "*"
+		} else if h.alternates > 0 {
+			ip = fmt.Sprintf("%s (+%d)", ip, h.alternates)
+		}
+		sb.WriteString(fmt.Sprintf("| %3d | %-39s | %-35s | %-25s | %10s | %5.0f%% |\n",
+			i+1, ip, truncateLabel(h.dns, 35), truncateLabel(asLabel(h.asn, h.asName), 25),
+			formatMicros(mean(h.latency)), h.loss()*100))
+	}
+	sb.WriteString("\n")
+}
+
+// comparePaths lists what changed on a path relative to its baseline:
+// different dominant hops, ASNs that appeared or disappeared, and hops
+// whose latency rose by at least 10 ms and 50%.
+func comparePaths(cur, base *tracePath) []string {
+	var changes []string
+	for i := 0; i < max(len(cur.hops), len(base.hops)); i++ {
+		var c, b traceHop
+		if i < len(cur.hops) {
+			c = cur.hops[i]
+		}
+		if i < len(base.hops) {
+			b = base.hops[i]
+		}
+		if c.ip != b.ip && (c.ip != "" || b.ip != "") {
+			changes = append(changes, fmt.Sprintf("hop %d: %s → %s", i+1, hopLabel(b), hopLabel(c)))
+			continue
+		}
+		cl, bl := mean(c.latency), mean(b.latency)
+		if cl >= 0 && bl >= 0 && cl-bl >= 10000 && cl >= bl*1.5 {
+			changes = append(changes, fmt.Sprintf("hop %d (%s): latency %s → %s", i+1, c.ip, formatMicros(bl), formatMicros(cl)))
+		}
+	}
+
+	curAS, baseAS := make(map[int]string), make(map[int]string)
+	for _, h := range cur.asPath() {
+		curAS[h.asn] = h.asName
+	}
+	for _, h := range base.asPath() {
+		baseAS[h.asn] = h.asName
+	}
+	var added, removed []string
+	for asn, name := range curAS {
+		if _, ok := baseAS[asn]; !ok {
+			added = append(added, asLabel(asn, name))
+		}
+	}
+	for asn, name := range baseAS {
+		if _, ok := curAS[asn]; !ok {
+			removed = append(removed, asLabel(asn, name))
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	if len(added) > 0 {
+		changes = append(changes, "new ASNs: "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, "ASNs no longer on path: "+strings.Join(removed, ", "))
+	}
+	if ce, be := cur.endLatency(), base.endLatency(); ce >= 0 && be >= 0 && ce-be >= 10000 && ce >= be*1.5 {
+		changes = append(changes, fmt.Sprintf("end-to-end latency %s → %s", formatMicros(be), formatMicros(ce)))
+	}
+	return changes
+}
+
+func hopLabel(h traceHop) string {
+	if h.ip == "" {
+		return "*"
+	}
+	if h.asn != 0 {
+		return fmt.Sprintf("%s (AS%d)", h.ip, h.asn)
+	}
+	return h.ip
+}
+
+// baselineWindow reads baseline_start_time/baseline_end_time or
+// baseline_offset. ok is false when no baseline was requested.
+func baselineWindow(request mcp.CallToolRequest, current timeWindow) (w timeWindow, ok bool, err error) {
+	bs, _ := request.RequireString("baseline_start_time")
+	be, _ := request.RequireString("baseline_end_time")
+	if bs != "" || be != "" {
+		if bs == "" || be == "" {
+			return w, true, fmt.Errorf("baseline_start_time and baseline_end_time must be set together")
+		}
+		if w.Start, err = parseTime(bs); err != nil {
+			return w, true, fmt.Errorf("invalid baseline_start_time: %v", err)
+		}
+		if w.End, err = parseTime(be); err != nil {
+			return w, true, fmt.Errorf("invalid baseline_end_time: %v", err)
+		}
+		if !w.Start.Before(w.End) {
+			return w, true, fmt.Errorf("baseline start must be before baseline end")
+		}
+		return w, true, nil
+	}
+	offset, _ := request.RequireString("baseline_offset")
+	if offset == "" {
+		return w, false, nil
+	}
+	d, err := parseHumanDuration(offset)
+	if err != nil {
+		return w, true, fmt.Errorf("invalid baseline_offset: %v", err)
+	}
+	return timeWindow{Start: current.Start.Add(-d), End: current.End.Add(-d)}, true, nil
+}
+
+// withBaselineParams adds the baseline window parameters.
+func withBaselineParams(what string) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("baseline_offset",
+			mcp.Description(fmt.Sprintf("Compare %s with the same window shifted back by this duration, e.g. '1d' or '1w'", what)),
+		)(t)
+		mcp.WithString("baseline_start_time",
+			mcp.Description("Explicit baseline start (RFC3339), instead of baseline_offset"),
+		)(t)
+		mcp.WithString("baseline_end_time",
+			mcp.Description("Explicit baseline end (RFC3339), instead of baseline_offset"),
+		)(t)
+	}
+}
+
+// formatTraceAnalysis renders the reconstructed paths and, with a
+// baseline, the changes per agent.
+func formatTraceAnalysis(window timeWindow, paths []*tracePath, baseWindow *timeWindow, basePaths []*tracePath, agentNames map[string]string) string {
+	name := func(id string) string {
+		if n := agentNames[id]; n != "" {
+			return n
+		}
+		return id
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Synthetic Trace Analysis\n\n")
+	sb.WriteString(fmt.Sprintf("Window: %s\n", window))
+	if baseWindow != nil {
+		sb.WriteString(fmt.Sprintf("Baseline: %s\n", baseWindow))
+	}
+	sb.WriteString("\n")
+	if len(paths) == 0 {
+		sb.WriteString("No traces in this window.\n")
+		return sb.String()
+	}
+
+	if baseWindow != nil {
+		base := make(map[string]*tracePath)
+		for _, p := range basePaths {
+			base[p.key()] = p
+		}
+		sb.WriteString("### Path changes vs baseline\n\n")
+		compared, changed := 0, 0
+		var unmatched []string
+		for _, p := range paths {
+			b, ok := base[p.key()]
+			if !ok {
+				unmatched = append(unmatched, fmt.Sprintf("%s → %s", name(p.agentID), p.target))
+				continue
+			}
+			compared++
+			changes := comparePaths(p, b)
+			if len(changes) == 0 {
+				continue
+			}
+			changed++
+			sb.WriteString(fmt.Sprintf("- **%s → %s**\n", name(p.agentID), p.target))
+			for _, c := range changes {
+				sb.WriteString("  - " + c + "\n")
+			}
+		}
+		if compared > 0 && changed == 0 {
+			sb.WriteString(fmt.Sprintf("No hop, ASN or latency changes on %d compared path(s).\n", compared))
+		}
+		if len(unmatched) > 0 {
+			sb.WriteString(fmt.Sprintf("\nNot compared, no baseline traces (%d):\n", len(unmatched)))
+			for _, u := range unmatched {
+				sb.WriteString("- " + u + "\n")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, p := range paths {
+		writeTracePath(&sb, p, name(p.agentID))
+	}
+	return sb.String()
+}
+
+// filterTracePaths keeps the paths of agents matching a comma-separated
+// list of names (substring) or IDs.
+func filterTracePaths(paths []*tracePath, filter string, agentNames map[string]string) []*tracePath {
+	terms := splitCSV(filter)
+	if len(terms) == 0 {
+		return paths
+	}
+	var out []*tracePath
+	for _, p := range paths {
+		for _, t := range terms {
+			if p.agentID == t || strings.Contains(strings.ToLower(agentNames[p.agentID]), strings.ToLower(t)) {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatTraceAnalysisCountsComparedPaths(t *testing.T) {
+	hops := []traceHop{{ip: "192.0.2.1", asn: 64500, latency: []float64{1000}, probes: 1, responses: 1}}
+	path := func(agent string) *tracePath {
+		return &tracePath{agentID: agent, target: "198.51.100.1", traces: 1, complete: 1, variants: 1, hops: hops}
+	}
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	window := timeWindow{Start: start, End: start.Add(time.Hour)}
+	base := timeWindow{Start: start.Add(-24 * time.Hour), End: start.Add(-23 * time.Hour)}
+	names := map[string]string{"1": "fra", "2": "ams", "3": "lon"}
+
+	out := formatTraceAnalysis(window, []*tracePath{path("1"), path("2"), path("3")}, &base, []*tracePath{path("1")}, names)
+	for _, want := range []string{
+		"No hop, ASN or latency changes on 1 compared path(s).",
+		"Not compared, no baseline traces (2):\n- ams → 198.51.100.1\n- lon → 198.51.100.1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	out = formatTraceAnalysis(window, []*tracePath{path("2")}, &base, []*tracePath{path("1")}, names)
+	if strings.Contains(out, "No hop, ASN or latency changes") {
+		t.Errorf("claims no changes without any compared path:\n%s", out)
+	}
+}
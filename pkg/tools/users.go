@@ -12,11 +12,13 @@ import (
 func registerUserTools(s *server.MCPServer, client *kentik.Client) {
 	listUsers := mcp.NewTool("kentik_list_users",
 		mcp.WithDescription("List all users registered in the Kentik organization."),
+		readOnlyTool(),
 	)
 	s.AddTool(listUsers, makeListUsersHandler(client))
 
 	getUser := mcp.NewTool("kentik_get_user",
 		mcp.WithDescription("Get information about a specific user by ID."),
+		readOnlyTool(),
 		mcp.WithString("user_id",
 			mcp.Required(),
 			mcp.Description("The ID of the user"),
@@ -27,7 +29,7 @@ func registerUserTools(s *server.MCPServer, client *kentik.Client) {
 
 func makeListUsersHandler(client *kentik.Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		data, err := client.V5("GET", "/users", nil)
+		data, err := client.V5(ctx, "GET", "/users", nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to list users: %v", err)), nil
 		}
@@ -41,7 +43,7 @@ func makeGetUserHandler(client *kentik.Client) server.ToolHandlerFunc {
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		data, err := client.V5("GET", fmt.Sprintf("/user/%s", userID), nil)
+		data, err := client.V5(ctx, "GET", fmt.Sprintf("/user/%s", userID), nil)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to get user: %v", err)), nil
 		}
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// timeWindow is a resolved [Start, End) range in UTC.
+type timeWindow struct {
+	Start, End time.Time
+}
+
+// String renders the window for tool output, e.g.
+// "2025-01-01 10:00:00 → 2025-01-01 12:00:00 UTC (2h00m)".
+func (w timeWindow) String() string {
+	return fmt.Sprintf("%s → %s UTC (%s)", w.Start.Format(time.DateTime), w.End.Format(time.DateTime), formatDuration(w.End.Sub(w.Start)))
+}
+
+// rfc3339 formats t for the synthetics API.
+func rfc3339(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// withWindowParams adds the time window parameters shared by the
+// synthetics tools.
+func withWindowParams(opts Options) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("start_time",
+			mcp.Description("Start time, RFC3339 (e.g. 2025-01-01T00:00:00Z). Optional; overrides lookback."),
+		)(t)
+		mcp.WithString("end_time",
+			mcp.Description("End time, RFC3339. Default: now"),
+		)(t)
+		mcp.WithNumber("lookback_seconds",
+			mcp.Description(fmt.Sprintf("Window length ending at end_time. Default: %d", int(opts.lookback()))),
+		)(t)
+		mcp.WithString("lookback",
+			mcp.Description("Window as a human duration instead of lookback_seconds: '15m', '2h', '1d', '1w', 'today', 'yesterday', 'this_month' or 'last_month' (UTC calendar)."),
+		)(t)
+	}
+}
+
+// calendarLookbacks are the lookback keywords that fix both ends of the
+// window, so they cannot be combined with start_time or end_time.
+var calendarLookbacks = map[string]bool{"today": true, "yesterday": true, "this_month": true, "last_month": true}
+
+// resolveWindow reads the withWindowParams parameters. Explicit start and
+// end times win; otherwise the window is lookback (or lookback_seconds)
+// long and ends at end_time or now.
+func resolveWindow(ctx context.Context, request mcp.CallToolRequest) (timeWindow, error) {
+	return resolveWindowAt(request, time.Now(), optionsFrom(ctx).lookback())
+}
+
+// resolveWindowAt is resolveWindow relative to now, with the given default
+// lookback in seconds.
+func resolveWindowAt(request mcp.CallToolRequest, now time.Time, lookback float64) (timeWindow, error) {
+	now = now.UTC().Truncate(time.Second)
+	if v, _ := request.RequireString("lookback"); calendarLookbacks[strings.ToLower(strings.TrimSpace(v))] {
+		for _, bound := range []string{"start_time", "end_time"} {
+			if b, _ := request.RequireString(bound); b != "" {
+				return timeWindow{}, fmt.Errorf("lookback '%s' cannot be combined with %s", v, bound)
+			}
+		}
+	}
+	end := now
+	if v, err := request.RequireString("end_time"); err == nil && v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return timeWindow{}, fmt.Errorf("invalid end_time: %v", err)
+		}
+		end = t
+	}
+
+	var start time.Time
+	if v, err := request.RequireString("start_time"); err == nil && v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return timeWindow{}, fmt.Errorf("invalid start_time: %v", err)
+		}
+		start = t
+	} else if v, err := request.RequireString("lookback"); err == nil && v != "" {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "today":
+			start = now.Truncate(24 * time.Hour)
+		case "yesterday":
+			end = now.Truncate(24 * time.Hour)
+			start = end.Add(-24 * time.Hour)
+		case "this_month":
+			start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		case "last_month":
+			end = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+			start = end.AddDate(0, -1, 0)
+		default:
+			d, err := parseHumanDuration(v)
+			if err != nil {
+				return timeWindow{}, fmt.Errorf("invalid lookback: %v", err)
+			}
+			start = end.Add(-d)
+		}
+	} else {
+		if lb, err := request.RequireFloat("lookback_seconds"); err == nil {
+			if lb <= 0 {
+				return timeWindow{}, fmt.Errorf("lookback_seconds must be positive")
+			}
+			lookback = lb
+		}
+		start = end.Add(-time.Duration(lookback) * time.Second)
+	}
+
+	if !start.Before(end) {
+		return timeWindow{}, fmt.Errorf("start (%s) must be before end (%s)", rfc3339(start), rfc3339(end))
+	}
+	if start.After(now) {
+		return timeWindow{}, fmt.Errorf("start (%s) is in the future", rfc3339(start))
+	}
+	if end.After(now) {
+		end = now
+	}
+	return timeWindow{Start: start, End: end}, nil
+}
+
+// parseTime accepts RFC3339, or a date and time without zone taken as UTC.
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", time.DateTime, "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC3339 time (e.g. 2025-01-01T00:00:00Z)", s)
+}
+
+// parseHumanDuration parses Go durations plus "d" (days) and "w" (weeks)
+// units, e.g. "90m", "2h", "1d", "2w", or a bare number of seconds.
+func parseHumanDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "last ")
+	if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if v, err := strconv.ParseFloat(n, 64); err == nil && v > 0 {
+				return time.Duration(v * float64(unit)), nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is not a duration like 30m, 2h, 1d or 1w", s)
+	}
+	return d, nil
+}
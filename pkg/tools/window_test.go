@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func toolRequest(args map[string]any) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Arguments = args
+	return request
+}
+
+func TestResolveWindow(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 30, 0, 0, time.UTC)
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	tests := []struct {
+		name       string
+		args       map[string]any
+		start, end string
+		err        string
+	}{
+		{"lookback_seconds", map[string]any{"lookback_seconds": 7200.0}, "2025-03-15T10:30:00Z", "2025-03-15T12:30:00Z", ""},
+		{"human lookback", map[string]any{"lookback": "1d"}, "2025-03-14T12:30:00Z", "2025-03-15T12:30:00Z", ""},
+		{"lookback before end_time", map[string]any{"lookback": "2h", "end_time": "2025-03-15T06:00:00Z"}, "2025-03-15T04:00:00Z", "2025-03-15T06:00:00Z", ""},
+		{"explicit bounds", map[string]any{"start_time": "2025-03-01 00:00", "end_time": "2025-03-02T00:00:00Z"}, "2025-03-01T00:00:00Z", "2025-03-02T00:00:00Z", ""},
+		{"end clamped to now", map[string]any{"start_time": "2025-03-15T12:00:00Z", "end_time": "2025-03-16T00:00:00Z"}, "2025-03-15T12:00:00Z", "2025-03-15T12:30:00Z", ""},
+		{"today", map[string]any{"lookback": "today"}, "2025-03-15T00:00:00Z", "2025-03-15T12:30:00Z", ""},
+		{"yesterday", map[string]any{"lookback": "Yesterday"}, "2025-03-14T00:00:00Z", "2025-03-15T00:00:00Z", ""},
+		{"this_month", map[string]any{"lookback": "this_month"}, "2025-03-01T00:00:00Z", "2025-03-15T12:30:00Z", ""},
+		{"last_month", map[string]any{"lookback": "last_month"}, "2025-02-01T00:00:00Z", "2025-03-01T00:00:00Z", ""},
+		{"calendar with end_time", map[string]any{"lookback": "yesterday", "end_time": "2025-03-10T00:00:00Z"}, "", "", "cannot be combined with end_time"},
+		{"calendar with start_time", map[string]any{"lookback": "last_month", "start_time": "2025-01-01T00:00:00Z"}, "", "", "cannot be combined with start_time"},
+		{"start after end", map[string]any{"start_time": "2025-03-15T10:00:00Z", "end_time": "2025-03-15T09:00:00Z"}, "", "", "must be before end"},
+		{"start in the future", map[string]any{"start_time": "2025-03-16T00:00:00Z", "end_time": "2025-03-17T00:00:00Z"}, "", "", "in the future"},
+		{"negative lookback_seconds", map[string]any{"lookback_seconds": -5.0}, "", "", "must be positive"},
+		{"bad lookback", map[string]any{"lookback": "fortnight"}, "", "", "invalid lookback"},
+		{"bad start_time", map[string]any{"start_time": "yesterday"}, "", "", "invalid start_time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := resolveWindowAt(toolRequest(tt.args), now, 3600)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !w.Start.Equal(at(tt.start)) || !w.End.Equal(at(tt.end)) {
+				t.Errorf("window = %s, want %s → %s", w, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParseHumanDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"90m", 90 * time.Minute, true},
+		{"2h", 2 * time.Hour, true},
+		{"1d", 24 * time.Hour, true},
+		{"last 2w", 14 * 24 * time.Hour, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"3600", time.Hour, true},
+		{"0", 0, false},
+		{"-1h", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseHumanDuration(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseHumanDuration(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/awlx/kentik-mcp/pkg/config"
+	"github.com/awlx/kentik-mcp/pkg/kentik"
+	"github.com/awlx/kentik-mcp/pkg/tools"
+)
+
+// tokenPollInterval is how often api_token_file modification times are checked.
+const tokenPollInterval = 10 * time.Second
+
+// watchCredentials reloads the tokens of accounts that read them from a file
+// or command: all of them on SIGHUP, and file-based ones whenever the file
+// changes. accounts must be in cfg.Accounts order.
+func watchCredentials(cfg *config.Config, accounts []tools.Account) {
+	var rotatable []int
+	for i, a := range cfg.Accounts {
+		if a.Rotatable() {
+			rotatable = append(rotatable, i)
+		}
+	}
+	if len(rotatable) == 0 {
+		return
+	}
+
+	reload := func(i int, reason string) {
+		token, err := cfg.Accounts[i].ReadToken()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kentik-mcp: token reload for account %s failed (%s): %s\n",
+				accounts[i].Name, reason, kentik.Redact(err.Error()))
+			return
+		}
+		accounts[i].Client.SetAPIToken(token)
+		fmt.Fprintf(os.Stderr, "kentik-mcp: reloaded token for account %s (%s)\n", accounts[i].Name, reason)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	modTimes := make(map[int]time.Time)
+	for _, i := range rotatable {
+		if f := cfg.Accounts[i].TokenFile(); f != "" {
+			modTimes[i] = time.Time{}
+			if st, err := os.Stat(f); err == nil {
+				modTimes[i] = st.ModTime()
+			}
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(tokenPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hup:
+				for _, i := range rotatable {
+					reload(i, "SIGHUP")
+				}
+			case <-ticker.C:
+				for i, last := range modTimes {
+					st, err := os.Stat(cfg.Accounts[i].TokenFile())
+					if err != nil || st.ModTime().Equal(last) {
+						continue
+					}
+					modTimes[i] = st.ModTime()
+					reload(i, "token file changed")
+				}
+			}
+		}
+	}()
+}